@@ -7,9 +7,9 @@ toolchain go1.24.4
 require (
 	github.com/elliotchance/orderedmap v1.4.0
 	github.com/go-sql-driver/mysql v1.8.1
-	github.com/jmoiron/sqlx v1.4.0
 	github.com/stretchr/testify v1.8.4
 	golang.org/x/crypto v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -38,5 +38,4 @@ require (
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/telemetry v0.0.0-20241106142447-58a1122356f5 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -13,6 +13,7 @@ const (
 	alterTypeCreate
 	alterTypeDropTable
 	alterTypeAlter
+	alterTypeRename
 )
 
 func (at alterType) String() string {
@@ -25,6 +26,8 @@ func (at alterType) String() string {
 		return "drop"
 	case alterTypeAlter:
 		return "alter"
+	case alterTypeRename:
+		return "rename"
 	default:
 		return "unknown"
 	}
@@ -37,17 +40,36 @@ type TableAlterData struct {
 	Comment    string
 	SQL        []string
 	Type       alterType
+	Changes    []*schemaChange
+
+	// RenameFrom 新建的表可能由目标数据库的此表重命名而来
+	RenameFrom string
+
+	// online 大表的在线变更方式，影子表复制的语句需要依次执行
+	online string
 }
 
+// Split 每条语句拆分为一个 TableAlterData，single_schema_change 时每条语句只包含对应的变更项
 func (ta *TableAlterData) Split() []*TableAlterData {
+	var owners []*schemaChange
+	for _, ch := range ta.Changes {
+		for range ch.clauses() {
+			owners = append(owners, ch)
+		}
+	}
 	rs := make([]*TableAlterData, len(ta.SQL))
 	for i := 0; i < len(ta.SQL); i++ {
+		changes := ta.Changes
+		if len(owners) == len(ta.SQL) {
+			changes = []*schemaChange{owners[i]}
+		}
 		rs[i] = &TableAlterData{
 			SchemaDiff: ta.SchemaDiff,
 			Table:      ta.Table,
 			Comment:    ta.Comment,
 			Type:       ta.Type,
 			SQL:        []string{ta.SQL[i]},
+			Changes:    changes,
 		}
 	}
 	return rs
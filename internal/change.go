@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// changeKind 单项变更的类型
+type changeKind string
+
+const (
+	changeTableCreate    changeKind = "table_create"
+	changeTableDrop      changeKind = "table_drop"
+	changeTableOptions   changeKind = "table_options"
+	changeTableRename    changeKind = "table_rename"
+	changeColumnAdd      changeKind = "column_add"
+	changeColumnChange   changeKind = "column_change"
+	changeColumnDrop     changeKind = "column_drop"
+	changeColumnRename   changeKind = "column_rename"
+	changeIndexAdd       changeKind = "index_add"
+	changeIndexReplace   changeKind = "index_replace"
+	changeIndexDrop      changeKind = "index_drop"
+	changeForeignAdd     changeKind = "foreign_key_add"
+	changeForeignReplace changeKind = "foreign_key_replace"
+	changeForeignDrop    changeKind = "foreign_key_drop"
+)
+
+// schemaChange 表结构的单项变更，Before 为目标库定义，After 为源库定义，
+// 变更的 sql 由变更项生成，报告、回滚、在线变更等都基于变更项处理
+type schemaChange struct {
+	Kind   changeKind `json:"kind"`
+	Name   string     `json:"name,omitempty"`
+	Before string     `json:"before,omitempty"`
+	After  string     `json:"after,omitempty"`
+
+	// From 重命名的字段在目标库中的原名
+	From string `json:"from,omitempty"`
+
+	// Position 字段的位置：FIRST、AFTER `字段名`，新增字段为在源库中的位置，删除字段为在目标库中的位置
+	Position string `json:"position,omitempty"`
+
+	// Risk 变更的风险等级，RiskReason 为风险的说明，如类型的变化
+	Risk       riskLevel `json:"risk"`
+	RiskReason string    `json:"risk_reason,omitempty"`
+}
+
+func (ta *TableAlterData) addChange(kind changeKind, name string, before string, after string) *schemaChange {
+	ch := &schemaChange{
+		Kind:   kind,
+		Name:   name,
+		Before: before,
+		After:  after,
+	}
+	ta.Changes = append(ta.Changes, ch)
+	return ch
+}
+
+// clauses 变更在 ALTER TABLE 中的子句，表级别的变更（新建、删除、重命名）没有子句
+func (ch *schemaChange) clauses() []string {
+	switch ch.Kind {
+	case changeTableOptions:
+		return []string{strings.TrimSpace(strings.TrimPrefix(ch.After, ")"))}
+	case changeColumnAdd:
+		if len(ch.Position) == 0 {
+			return []string{"ADD " + ch.After}
+		}
+		return []string{"ADD " + ch.After + " " + ch.Position}
+	case changeColumnChange:
+		return []string{fmt.Sprintf("CHANGE `%s` %s", ch.Name, ch.After)}
+	case changeColumnRename:
+		return []string{fmt.Sprintf("CHANGE `%s` %s", ch.From, ch.After)}
+	case changeColumnDrop:
+		return []string{fmt.Sprintf("drop `%s`", ch.Name)}
+	case changeIndexAdd, changeForeignAdd:
+		return parseDbIndexLine(ch.After).alterAddSQL(false)
+	case changeIndexReplace, changeForeignReplace:
+		return parseDbIndexLine(ch.After).alterAddSQL(true)
+	case changeIndexDrop, changeForeignDrop:
+		return []string{parseDbIndexLine(ch.Before).alterDropSQL()}
+	}
+	return nil
+}
+
+// inverse 恢复目标库原定义的逆变更，表级别的变更返回 nil
+func (ch *schemaChange) inverse() *schemaChange {
+	inv := &schemaChange{Kind: ch.Kind, Name: ch.Name, Before: ch.After, After: ch.Before}
+	switch ch.Kind {
+	case changeTableOptions, changeColumnChange, changeIndexReplace, changeForeignReplace:
+	case changeColumnAdd:
+		inv.Kind = changeColumnDrop
+	case changeColumnDrop:
+		inv.Kind = changeColumnAdd
+		inv.Position = ch.Position
+	case changeColumnRename:
+		inv.Name, inv.From = ch.From, ch.Name
+	case changeIndexAdd:
+		inv.Kind = changeIndexDrop
+	case changeIndexDrop:
+		inv.Kind = changeIndexAdd
+	case changeForeignAdd:
+		inv.Kind = changeForeignDrop
+	case changeForeignDrop:
+		inv.Kind = changeForeignAdd
+	default:
+		return nil
+	}
+	return inv
+}
+
+// alterTableSQL 由变更项生成表 table 的变更语句，字段、索引、外键的变更合并为一条 ALTER TABLE，
+// single 时每个子句单独一条语句
+func alterTableSQL(table string, changes []*schemaChange, single bool) []string {
+	var sqls, clauses []string
+	for _, ch := range changes {
+		switch ch.Kind {
+		case changeTableCreate:
+			sqls = append(sqls, ch.After+";")
+		case changeTableDrop:
+			sqls = append(sqls, fmt.Sprintf("drop table `%s`;", table))
+		case changeTableRename:
+			sqls = append(sqls, fmt.Sprintf("RENAME TABLE `%s` TO `%s`;", ch.Before, ch.After))
+		case changeTableOptions:
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` %s;", table, ch.clauses()[0]))
+		default:
+			clauses = append(clauses, ch.clauses()...)
+		}
+	}
+	if len(clauses) == 0 {
+		return sqls
+	}
+	if single {
+		for _, clause := range clauses {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s`\n%s;", table, clause))
+		}
+		return sqls
+	}
+	return append(sqls, fmt.Sprintf("ALTER TABLE `%s`\n%s;", table, strings.Join(clauses, ",\n")))
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_schemaChange_inverse(t *testing.T) {
+	changes := []*schemaChange{
+		{Kind: changeColumnRename, Name: "passwd", From: "password", Before: "`password` varchar(255)", After: "`passwd` varchar(255)"},
+		{Kind: changeColumnAdd, Name: "age", After: "`age` int", Position: "FIRST"},
+		{Kind: changeColumnDrop, Name: "status", Before: "`status` int", Position: "AFTER `passwd`"},
+		{Kind: changeIndexReplace, Name: "idx_a", Before: "KEY `idx_a` (`a`)", After: "KEY `idx_a` (`a`,`b`)"},
+		{Kind: changeForeignAdd, Name: "fk_1", After: "CONSTRAINT `fk_1` FOREIGN KEY (`uid`) REFERENCES `user` (`id`)"},
+	}
+	require.Equal(t, []string{"ALTER TABLE `t`\n" +
+		"CHANGE `password` `passwd` varchar(255),\n" +
+		"ADD `age` int FIRST,\n" +
+		"drop `status`,\n" +
+		"DROP INDEX `idx_a`,\n" +
+		"ADD KEY `idx_a` (`a`,`b`),\n" +
+		"ADD CONSTRAINT `fk_1` FOREIGN KEY (`uid`) REFERENCES `user` (`id`);",
+	}, alterTableSQL("t", changes, false))
+
+	var inverses []*schemaChange
+	for _, ch := range changes {
+		inverses = append(inverses, ch.inverse())
+	}
+	require.Equal(t, []string{
+		"ALTER TABLE `t`\nCHANGE `passwd` `password` varchar(255);",
+		"ALTER TABLE `t`\ndrop `age`;",
+		"ALTER TABLE `t`\nADD `status` int AFTER `passwd`;",
+		"ALTER TABLE `t`\nDROP INDEX `idx_a`;",
+		"ALTER TABLE `t`\nADD KEY `idx_a` (`a`);",
+		"ALTER TABLE `t`\nDROP FOREIGN KEY `fk_1`;",
+	}, alterTableSQL("t", inverses, true))
+
+	require.Nil(t, (&schemaChange{Kind: changeTableCreate}).inverse())
+}
+
+func Test_alterTableSQL_tableOptions(t *testing.T) {
+	sc := &SchemaSync{Config: &Config{}}
+	sd := sc.getAlterDataBySchema("user",
+		"CREATE TABLE `user` (\n`id` int\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		"CREATE TABLE `user` (\n`id` int\n) ENGINE=InnoDB AUTO_INCREMENT=3 DEFAULT CHARSET=utf8mb3",
+		&Config{})
+	require.Equal(t, []string{"ALTER TABLE `user` ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;"}, sd.SQL)
+	require.Equal(t, []string{"ALTER TABLE `user` ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;"}, alterRollbackSQL(sd))
+}
+
+func TestTableAlterData_Split(t *testing.T) {
+	sc := &SchemaSync{Config: &Config{Drop: true}}
+	sd := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_6.sql"), testLoadFile("testdata/user_1.sql"), &Config{SingleSchemaChange: true})
+	sds := sd.Split()
+	require.Len(t, sds, 4)
+	for i, s := range sds {
+		require.Equal(t, []*schemaChange{sd.Changes[i]}, s.Changes)
+		require.Equal(t, alterTableSQL("user", s.Changes, true), s.SQL)
+	}
+}
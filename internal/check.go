@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+)
+
+// 检查模式（-check）下的退出码，同时存在多种差异时按 执行错误 > 结构差异 > 数据差异 > 存储过程差异 的优先级返回，
+// 存储过程差异包含存储过程、函数和事件。从 10 开始，避免与 log.Fatal（1）、参数错误（2）的退出码混淆
+const (
+	ExitNoDrift        = 0
+	ExitSchemaDrift    = 10
+	ExitDataDrift      = 11
+	ExitProcedureDrift = 12
+	ExitExecError      = 13
+)
+
+// driftSummary 统计本次运行发现的差异数量
+type driftSummary struct {
+	schemaDrift    int
+	dataDrift      int
+	procedureDrift int
+	execErrors     int
+}
+
+var drift = &driftSummary{}
+
+func (ds *driftSummary) String() string {
+	return fmt.Sprintf("schema_drift=%d data_drift=%d procedure_drift=%d exec_errors=%d",
+		ds.schemaDrift, ds.dataDrift, ds.procedureDrift, ds.execErrors)
+}
+
+func (ds *driftSummary) exitCode() int {
+	switch {
+	case ds.execErrors > 0:
+		return ExitExecError
+	case ds.schemaDrift > 0:
+		return ExitSchemaDrift
+	case ds.dataDrift > 0:
+		return ExitDataDrift
+	case ds.procedureDrift > 0:
+		return ExitProcedureDrift
+	default:
+		return ExitNoDrift
+	}
+}
+
+// CheckResult 输出一行差异汇总，并返回对应的退出码
+func CheckResult() int {
+	code := drift.exitCode()
+	result := "ok"
+	if code != ExitNoDrift {
+		result = "drift"
+		if code == ExitExecError {
+			result = "error"
+		}
+	}
+	fmt.Fprintf(os.Stderr, "# check %s: %s exit_code=%d\n", result, drift, code)
+	return code
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_driftSummary_exitCode(t *testing.T) {
+	// 退出码是 CI 使用的约定，使用字面值检查
+	tests := []struct {
+		name  string
+		drift driftSummary
+		want  int
+	}{
+		{"no drift", driftSummary{}, 0},
+		{"schema", driftSummary{schemaDrift: 1}, 10},
+		{"data", driftSummary{dataDrift: 2}, 11},
+		{"procedure", driftSummary{procedureDrift: 1}, 12},
+		{"exec error", driftSummary{execErrors: 1}, 13},
+		{"schema and data", driftSummary{schemaDrift: 1, dataDrift: 1}, 10},
+		{"data and procedure", driftSummary{dataDrift: 1, procedureDrift: 3}, 11},
+		{"schema, data and procedure", driftSummary{schemaDrift: 2, dataDrift: 1, procedureDrift: 1}, 10},
+		{"exec error and all drift", driftSummary{schemaDrift: 1, dataDrift: 1, procedureDrift: 1, execErrors: 1}, 13},
+		{"exec error and procedure", driftSummary{procedureDrift: 1, execErrors: 2}, 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.drift.exitCode())
+		})
+	}
+}
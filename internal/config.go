@@ -18,25 +18,118 @@ type Config struct {
 
 	ConfigPath string
 
-	// 要同步的数据库
+	// 要同步的数据库，源数据库和目标数据库名字不同时使用 源库名:目标库名
 	Schemas []string `json:"schemas"`
 
+	// SchemaMap 源数据库同步到目标服务器上不同名的数据库，格式：{"源库名": "目标库名"}
+	SchemaMap map[string]string `json:"schema_map"`
+
 	// Tables 同步表的白名单，若为空，则同步全库
 	Tables []string `json:"tables"`
 
 	// TablesIgnore 不同步的表
 	TablesIgnore []string `json:"tables_ignore"`
 
+	// TableMap 源数据库的表同步到目标数据库中不同名的表，格式：{"源表名": "目标表名"}
+	TableMap map[string]string `json:"table_map"`
+
 	TablesCompareData []string `json:"tables_compare_data"`
 
+	// DataCompare 表数据对比的设置，key 为表名，支持通配符
+	DataCompare map[string]*DataCompareOption `json:"data_compare"`
+
+	// Renames 声明字段的重命名，格式：{"表名": {"旧字段名": "新字段名"}}
+	Renames map[string]map[string]string `json:"renames"`
+
+	// Procedures 同步的存储过程、函数、事件的白名单，若为空，则同步全部
+	Procedures []string `json:"procedures"`
+
+	// ProceduresIgnore 不同步的存储过程、函数、事件
+	ProceduresIgnore []string `json:"procedures_ignore"`
+
+	// ViewIgnoreClauses 对比视图时忽略的子句：definer、algorithm、sql_security，不配置时全部忽略
+	ViewIgnoreClauses []string `json:"view_ignore_clauses"`
+
 	// Sync 是否真正的执行同步操作
 	Sync bool
 
 	// Drop 若目标数据库表比源头多了字段、索引，是否删除
 	Drop bool
 
+	// SyncData 是否生成（sync 时执行）使目标数据库 tables_compare_data 中的表数据与源头一致的语句
+	SyncData bool
+
+	// DropTableBackup drop 时将目标数据库多余的表重命名为 表名_dropped_日期 而不是删除
+	DropTableBackup bool `json:"drop_table_backup"`
+
+	// DropTableNonEmpty drop 时是否允许删除有数据的表，默认只删除空表
+	DropTableNonEmpty bool `json:"drop_table_non_empty"`
+
 	// SingleSchemaChange 生成sql ddl语言每条命令只会进行单个修改操作
 	SingleSchemaChange bool `json:"single_schema_change"`
+
+	// Format 对比结果的输出格式：text、json、ndjson
+	Format string
+
+	// OnError 执行语句出错时的处理方式：stop（默认）、continue
+	OnError string
+
+	// Online 大表使用在线变更（ALGORITHM=INPLACE/INSTANT 或影子表复制）
+	Online bool
+
+	// OnlineOption 在线变更的阈值设置
+	OnlineOption *OnlineOption `json:"online"`
+
+	// Preflight 变更前在目标库统计有问题的数据，会全表扫描
+	Preflight bool
+
+	// PreflightTimeout 单条检查语句的超时时间（秒），默认 30
+	PreflightTimeout int `json:"preflight_timeout"`
+
+	// Journal 执行日志文件，记录每条执行过的语句
+	Journal string
+
+	// Resume 跳过执行日志中已成功执行的语句
+	Resume bool
+
+	// MaxRisk 允许执行的最高风险等级：safe、rebuild、lossy、destructive，为空时不限制
+	MaxRisk string
+
+	// Allow 允许执行超过 MaxRisk 的变更，表名或 表名.字段名/索引名，支持通配符
+	Allow []string
+}
+
+// DataCompareOption 表数据对比的设置
+type DataCompareOption struct {
+	// IgnoreColumns 不参与对比的字段，如 updated_at
+	IgnoreColumns []string `json:"ignore_columns"`
+
+	// Where 只对比满足条件的行
+	Where string `json:"where"`
+
+	// KeyColumns 定位行的字段，需唯一，不配置时使用主键
+	KeyColumns []string `json:"key_columns"`
+}
+
+// dataCompareOption 表的数据对比设置，优先使用表名完全相同的设置，
+// 其次使用匹配的最长的通配符（长度相同时按字典序），结果与配置的顺序无关
+func (cfg *Config) dataCompareOption(table string) *DataCompareOption {
+	if opt, has := cfg.DataCompare[table]; has {
+		return opt
+	}
+	var matched string
+	for pattern := range cfg.DataCompare {
+		if !simpleMatch(pattern, table, "dataCompareOption") {
+			continue
+		}
+		if len(matched) == 0 || len(pattern) > len(matched) || (len(pattern) == len(matched) && pattern < matched) {
+			matched = pattern
+		}
+	}
+	if len(matched) > 0 {
+		return cfg.DataCompare[matched]
+	}
+	return &DataCompareOption{}
 }
 
 func (cfg *Config) String() string {
@@ -67,6 +160,54 @@ func (cfg *Config) SetTables(tables []string) {
 	}
 }
 
+// splitSchema 解析 schemas 中的一项，返回源数据库名和目标数据库名
+func (cfg *Config) splitSchema(schema string) (source string, dest string) {
+	if before, after, found := strings.Cut(schema, ":"); found {
+		return strings.TrimSpace(before), strings.TrimSpace(after)
+	}
+	schema = strings.TrimSpace(schema)
+	if name, has := cfg.SchemaMap[schema]; has {
+		return schema, name
+	}
+	return schema, schema
+}
+
+// schemaRefMap 所有需要改名的数据库，源库名 => 目标库名
+func (cfg *Config) schemaRefMap() map[string]string {
+	refs := make(map[string]string)
+	for src, dst := range cfg.SchemaMap {
+		refs[src] = dst
+	}
+	for _, schema := range cfg.Schemas {
+		src, dst := cfg.splitSchema(schema)
+		refs[src] = dst
+	}
+	for src, dst := range refs {
+		if src == dst {
+			delete(refs, src)
+		}
+	}
+	return refs
+}
+
+// destTableName 源数据库的表在目标数据库中对应的表名
+func (cfg *Config) destTableName(table string) string {
+	if name, has := cfg.TableMap[table]; has {
+		return name
+	}
+	return table
+}
+
+// sourceTableName 目标数据库的表在源数据库中对应的表名
+func (cfg *Config) sourceTableName(table string) string {
+	for src, dst := range cfg.TableMap {
+		if dst == table {
+			return src
+		}
+	}
+	return table
+}
+
 // SetTablesIgnore 设置忽略
 func (cfg *Config) SetTablesIgnore(tables []string) {
 	for _, name := range tables {
@@ -113,6 +254,28 @@ func (cfg *Config) CheckMatchIgnoreTables(name string) bool {
 	return false
 }
 
+// CheckMatchRoutines check procedure, function or event is match
+func (cfg *Config) CheckMatchRoutines(name string) bool {
+	if len(cfg.Procedures) > 0 {
+		var match bool
+		for _, pattern := range cfg.Procedures {
+			if simpleMatch(pattern, name, "CheckMatchRoutines") {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	for _, pattern := range cfg.ProceduresIgnore {
+		if simpleMatch(pattern, name, "CheckMatchRoutinesIgnore") {
+			return false
+		}
+	}
+	return true
+}
+
 // Check check config
 func (cfg *Config) Check() {
 	if len(cfg.SourceDSN) == 0 {
@@ -124,6 +287,42 @@ func (cfg *Config) Check() {
 	if len(cfg.Schemas) <= 0 {
 		log.Fatal("Schemas is empty")
 	}
+	if isSnapshotDSN(cfg.DestDSN) && cfg.Sync {
+		log.Fatal("dest is a schema snapshot, can not sync")
+	}
+	if (isSnapshotDSN(cfg.SourceDSN) || isSnapshotDSN(cfg.DestDSN)) && (len(cfg.TablesCompareData) > 0 || cfg.SyncData) {
+		log.Println("schema snapshot has no data, tables_compare_data is ignored")
+		cfg.TablesCompareData = nil
+		cfg.SyncData = false
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = formatText
+	case formatText, formatJSON, formatNDJSON:
+	default:
+		log.Fatal("unsupported format: ", cfg.Format)
+	}
+	output.format = cfg.Format
+
+	switch cfg.OnError {
+	case "":
+		cfg.OnError = onErrorStop
+	case onErrorStop, onErrorContinue:
+	default:
+		log.Fatal("unsupported on_error: ", cfg.OnError)
+	}
+	executor.onError = cfg.OnError
+
+	if _, ok := parseRiskLevel(cfg.MaxRisk); !ok && len(cfg.MaxRisk) > 0 {
+		log.Fatal("unsupported max_risk: ", cfg.MaxRisk)
+	}
+
+	if cfg.Resume && len(cfg.Journal) == 0 {
+		log.Fatal("journal is necessary when resume")
+	}
+	if len(cfg.Journal) > 0 && executor.journal == nil {
+		executor.journal = openJournal(cfg.Journal, cfg.Resume)
+	}
 }
 
 // LoadConfig load config file
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_CheckMatchRoutines(t *testing.T) {
+	cfg := &Config{}
+	require.True(t, cfg.CheckMatchRoutines("sp_daily_clean"))
+
+	cfg = &Config{
+		Procedures:       []string{"sp_*", "fn_rank"},
+		ProceduresIgnore: []string{"sp_tmp_*"},
+	}
+	require.True(t, cfg.CheckMatchRoutines("sp_daily_clean"))
+	require.True(t, cfg.CheckMatchRoutines("fn_rank"))
+	require.False(t, cfg.CheckMatchRoutines("fn_rank_v2"))
+	require.False(t, cfg.CheckMatchRoutines("sp_tmp_fix"))
+
+	cfg = &Config{
+		ProceduresIgnore: []string{"*_bak"},
+	}
+	require.True(t, cfg.CheckMatchRoutines("sp_daily_clean"))
+	require.False(t, cfg.CheckMatchRoutines("sp_daily_clean_bak"))
+}
+
+func TestConfig_splitSchema(t *testing.T) {
+	cfg := &Config{
+		Schemas:   []string{"game_config_db:game_config_db_t1", "game_main_db", "game_log_db"},
+		SchemaMap: map[string]string{"game_log_db": "game_log_db_t1"},
+	}
+	tests := []struct {
+		schema string
+		source string
+		dest   string
+	}{
+		{schema: "game_config_db:game_config_db_t1", source: "game_config_db", dest: "game_config_db_t1"},
+		{schema: "game_main_db", source: "game_main_db", dest: "game_main_db"},
+		{schema: "game_log_db", source: "game_log_db", dest: "game_log_db_t1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			source, dest := cfg.splitSchema(tt.schema)
+			require.Equal(t, tt.source, source)
+			require.Equal(t, tt.dest, dest)
+		})
+	}
+	require.Equal(t, map[string]string{
+		"game_config_db": "game_config_db_t1",
+		"game_log_db":    "game_log_db_t1",
+	}, cfg.schemaRefMap())
+
+	sc := &SchemaSync{Config: cfg}
+	require.Equal(t,
+		"CONSTRAINT `fk_club` FOREIGN KEY (`club_id`) REFERENCES `game_config_db_t1`.`club` (`id`)",
+		sc.rewriteSchemaRefs("CONSTRAINT `fk_club` FOREIGN KEY (`club_id`) REFERENCES `game_config_db`.`club` (`id`)"))
+
+	// 链式的映射只替换一次，结果不受 map 遍历顺序影响
+	sc = &SchemaSync{Config: &Config{Schemas: []string{"a:b", "b:c"}}}
+	for i := 0; i < 20; i++ {
+		require.Equal(t, "select `b`.`t`.`id`, `c`.`t2`.`id`, `d`.`t3`.`a` from `b`.`t` join `c`.`t2`",
+			sc.rewriteSchemaRefs("select `a`.`t`.`id`, `b`.`t2`.`id`, `d`.`t3`.`a` from `a`.`t` join `b`.`t2`"))
+	}
+}
+
+func TestConfig_dataCompareOption(t *testing.T) {
+	cfg := &Config{
+		DataCompare: map[string]*DataCompareOption{
+			"sys_*":    {IgnoreColumns: []string{"updated_at"}},
+			"sys_it*":  {IgnoreColumns: []string{"created_at"}},
+			"sys_i*":   {IgnoreColumns: []string{"deleted_at"}},
+			"sys_item": {Where: "deleted = 0", KeyColumns: []string{"code"}},
+		},
+	}
+	// 多个通配符匹配时使用最长的
+	for i := 0; i < 10; i++ {
+		require.Equal(t, []string{"created_at"}, cfg.dataCompareOption("sys_items").IgnoreColumns)
+	}
+	require.Equal(t, "deleted = 0", cfg.dataCompareOption("sys_item").Where)
+	require.Equal(t, []string{"updated_at"}, cfg.dataCompareOption("sys_user").IgnoreColumns)
+	require.Equal(t, &DataCompareOption{}, cfg.dataCompareOption("user"))
+}
@@ -0,0 +1,360 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	// dataChunkSize 数据对比时按主键顺序切分的每块行数
+	dataChunkSize = 1000
+
+	// dataLeafSize 块内行数不超过此值时逐行对比，否则继续二分
+	dataLeafSize = 64
+
+	// dataShowKeys 文本输出时每类差异最多展示的主键数
+	dataShowKeys = 20
+)
+
+// tableDataDiff 一张表的数据差异，主键值以字符串表示
+type tableDataDiff struct {
+	Table    string     `json:"table"`
+	Keys     []string   `json:"keys,omitempty"`
+	Inserted [][]string `json:"inserted,omitempty"`
+	Updated  [][]string `json:"updated,omitempty"`
+	Deleted  [][]string `json:"deleted,omitempty"`
+	Note     string     `json:"note,omitempty"`
+
+	// 目标数据库对应的表名
+	destTable string
+	// 参与对比的字段，两个库共有且没有被忽略的字段
+	columns []string
+	// 两个库共有的字段，同步数据时新增行使用
+	allColumns []string
+	// 目标数据库不存在此表
+	destMissing bool
+	option      *DataCompareOption
+}
+
+// where 配置的行过滤条件
+func (td *tableDataDiff) where() string {
+	if td.option == nil || len(strings.TrimSpace(td.option.Where)) == 0 {
+		return "1=1"
+	}
+	return "(" + td.option.Where + ")"
+}
+
+func (td *tableDataDiff) hasDiff() bool {
+	return len(td.Note) > 0 || len(td.Inserted)+len(td.Updated)+len(td.Deleted) > 0
+}
+
+func (td *tableDataDiff) String() string {
+	if len(td.Note) > 0 {
+		return fmt.Sprintf("# table `%s`: %s", td.Table, td.Note)
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# table `%s`: inserted %d, updated %d, deleted %d, key (%s)",
+		td.Table, len(td.Inserted), len(td.Updated), len(td.Deleted), strings.Join(td.Keys, ","))
+	for _, item := range []struct {
+		name string
+		keys [][]string
+	}{{"inserted", td.Inserted}, {"updated", td.Updated}, {"deleted", td.Deleted}} {
+		if len(item.keys) == 0 {
+			continue
+		}
+		var vals []string
+		for i, key := range item.keys {
+			if i == dataShowKeys {
+				vals = append(vals, fmt.Sprintf("... and %d more", len(item.keys)-dataShowKeys))
+				break
+			}
+			vals = append(vals, "("+strings.Join(key, ",")+")")
+		}
+		fmt.Fprintf(&buf, "\n#   %s: %s", item.name, strings.Join(vals, " "))
+	}
+	return buf.String()
+}
+
+// dataDiffer 按主键分块对比一张表在两个库中的数据：
+// 每块计算 行数 + BIT_XOR(CRC32(CONCAT_WS(...)))，不一致的块继续二分，直到块足够小时逐行对比
+type dataDiffer struct {
+	src    *MyDb
+	dst    *MyDb
+	result *tableDataDiff
+}
+
+// chunkSum 一个块的行数和校验值
+type chunkSum struct {
+	count int64
+	sum   int64
+}
+
+func (dd *dataDiffer) tableOf(db *MyDb) string {
+	if db == dd.dst {
+		return dd.result.destTable
+	}
+	return dd.result.Table
+}
+
+func (dd *dataDiffer) keyList() string {
+	return quoteColumns(dd.result.Keys)
+}
+
+// rowHashExpr 整行的校验表达式，NULL 与空字符串通过 ISNULL 区分
+func (dd *dataDiffer) rowHashExpr() string {
+	var parts []string
+	for _, col := range dd.result.columns {
+		parts = append(parts, fmt.Sprintf("`%s`", col), fmt.Sprintf("ISNULL(`%s`)", col))
+	}
+	return fmt.Sprintf("CRC32(CONCAT_WS('#', %s))", strings.Join(parts, ", "))
+}
+
+// rangeWhere 主键范围 (lo, hi] 的查询条件，nil 表示无边界
+func (dd *dataDiffer) rangeWhere(lo []string, hi []string) (string, []any) {
+	conds := []string{dd.result.where()}
+	var args []any
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(dd.result.Keys)), ",")
+	if lo != nil {
+		conds = append(conds, fmt.Sprintf("(%s) > (%s)", dd.keyList(), placeholders))
+		for _, v := range lo {
+			args = append(args, v)
+		}
+	}
+	if hi != nil {
+		conds = append(conds, fmt.Sprintf("(%s) <= (%s)", dd.keyList(), placeholders))
+		for _, v := range hi {
+			args = append(args, v)
+		}
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func (dd *dataDiffer) checksum(db *MyDb, lo []string, hi []string) chunkSum {
+	where, args := dd.rangeWhere(lo, hi)
+	query := fmt.Sprintf("SELECT COUNT(*), COALESCE(BIT_XOR(%s), 0) FROM `%s` WHERE %s",
+		dd.rowHashExpr(), dd.tableOf(db), where)
+	var cs chunkSum
+	if err := db.Db.QueryRow(query, args...).Scan(&cs.count, &cs.sum); err != nil {
+		log.Fatalln("checksum chunk failed:", query, err)
+	}
+	return cs
+}
+
+// boundary 范围 (lo, hi] 内按主键排序的第 offset 行（从 0 开始）的主键，不存在时返回 nil
+func (dd *dataDiffer) boundary(db *MyDb, lo []string, hi []string, offset int64) []string {
+	where, args := dd.rangeWhere(lo, hi)
+	query := fmt.Sprintf("SELECT %s FROM `%s` WHERE %s ORDER BY %s LIMIT 1 OFFSET %d",
+		dd.keyList(), dd.tableOf(db), where, dd.keyList(), offset)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		log.Fatalln("query chunk boundary failed:", query, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil
+	}
+	return scanStrings(rows, len(dd.result.Keys))
+}
+
+// rowHashes 范围 (lo, hi] 内每行的主键和校验值
+func (dd *dataDiffer) rowHashes(db *MyDb, lo []string, hi []string) (keys [][]string, hashes map[string]string) {
+	where, args := dd.rangeWhere(lo, hi)
+	query := fmt.Sprintf("SELECT %s, %s FROM `%s` WHERE %s ORDER BY %s",
+		dd.keyList(), dd.rowHashExpr(), dd.tableOf(db), where, dd.keyList())
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		log.Fatalln("query chunk rows failed:", query, err)
+	}
+	defer rows.Close()
+
+	hashes = make(map[string]string)
+	for rows.Next() {
+		vals := scanStrings(rows, len(dd.result.Keys)+1)
+		key := vals[:len(vals)-1]
+		keys = append(keys, key)
+		hashes[strings.Join(key, "\x00")] = vals[len(vals)-1]
+	}
+	return keys, hashes
+}
+
+func (dd *dataDiffer) compareRows(lo []string, hi []string) {
+	srcKeys, srcHashes := dd.rowHashes(dd.src, lo, hi)
+	dstKeys, dstHashes := dd.rowHashes(dd.dst, lo, hi)
+	for _, key := range srcKeys {
+		dstHash, has := dstHashes[strings.Join(key, "\x00")]
+		if !has {
+			dd.result.Inserted = append(dd.result.Inserted, key)
+		} else if dstHash != srcHashes[strings.Join(key, "\x00")] {
+			dd.result.Updated = append(dd.result.Updated, key)
+		}
+	}
+	for _, key := range dstKeys {
+		if _, has := srcHashes[strings.Join(key, "\x00")]; !has {
+			dd.result.Deleted = append(dd.result.Deleted, key)
+		}
+	}
+}
+
+// compareRange 对比范围 (lo, hi] 内的数据，不一致时二分缩小范围
+func (dd *dataDiffer) compareRange(lo []string, hi []string) {
+	srcSum := dd.checksum(dd.src, lo, hi)
+	dstSum := dd.checksum(dd.dst, lo, hi)
+	if srcSum == dstSum {
+		return
+	}
+	if max(srcSum.count, dstSum.count) <= dataLeafSize {
+		dd.compareRows(lo, hi)
+		return
+	}
+
+	// 以行数多的一方的中间行作为分割点
+	db, count := dd.src, srcSum.count
+	if dstSum.count > srcSum.count {
+		db, count = dd.dst, dstSum.count
+	}
+	mid := dd.boundary(db, lo, hi, count/2-1)
+	dd.compareRange(lo, mid)
+	dd.compareRange(mid, hi)
+}
+
+func (dd *dataDiffer) run() {
+	var lo []string
+	for {
+		hi := dd.boundary(dd.src, lo, nil, dataChunkSize-1)
+		// 最后一块不设上界，包含目标库中主键更大的行
+		dd.compareRange(lo, hi)
+		if hi == nil {
+			return
+		}
+		lo = hi
+	}
+}
+
+// missingColumns keys 中不在每组 columns 中的字段
+func missingColumns(keys []string, columns ...[]string) []string {
+	var missing []string
+	for _, key := range keys {
+		for _, cols := range columns {
+			if !inStringSlice(key, cols) {
+				missing = append(missing, key)
+				break
+			}
+		}
+	}
+	return missing
+}
+
+// compareTableData 对比一张表的数据
+func (sc *SchemaSync) compareTableData(table string, destTable string, opt *DataCompareOption) *tableDataDiff {
+	result := &tableDataDiff{
+		Table:     table,
+		destTable: destTable,
+		option:    opt,
+	}
+	if !inStringSlice(destTable, sc.DestDb.GetTableNames()) {
+		result.Note = "目标数据库不存在此表"
+		result.destMissing = true
+		return result
+	}
+
+	srcColumns := sc.SourceDb.GetColumnNames(table)
+	dstColumns := sc.DestDb.GetColumnNames(destTable)
+	if missing := missingColumns(opt.KeyColumns, srcColumns, dstColumns); len(missing) > 0 {
+		result.Note = "key_columns 中的字段在源库或目标库中不存在：" + strings.Join(missing, ",")
+		return result
+	}
+
+	srcKeys := opt.KeyColumns
+	dstKeys := opt.KeyColumns
+	if len(srcKeys) == 0 {
+		srcKeys = sc.SourceDb.GetPrimaryKeys(table)
+		dstKeys = sc.DestDb.GetPrimaryKeys(destTable)
+	}
+	if len(srcKeys) == 0 || strings.Join(srcKeys, ",") != strings.Join(dstKeys, ",") {
+		// 没有主键或主键不一致时无法按行对比，退化为 CHECKSUM TABLE，此时忽略字段和过滤条件都不生效
+		if sc.SourceDb.ChecksumTable(table) != sc.DestDb.ChecksumTable(destTable) {
+			result.Note = "主键不存在或不一致，CHECKSUM TABLE 不同"
+		}
+		return result
+	}
+	result.Keys = srcKeys
+
+	for _, col := range srcColumns {
+		if !inStringSlice(col, dstColumns) {
+			continue
+		}
+		result.allColumns = append(result.allColumns, col)
+		if !inStringSlice(col, opt.IgnoreColumns) || inStringSlice(col, srcKeys) {
+			result.columns = append(result.columns, col)
+		}
+	}
+
+	dd := &dataDiffer{
+		src:    sc.SourceDb,
+		dst:    sc.DestDb,
+		result: result,
+	}
+	dd.run()
+	return result
+}
+
+// CheckDiffData 对比 tables_compare_data 中的表的数据，输出有差异的表及差异行的主键
+func (sc *SchemaSync) CheckDiffData(cfg *Config) {
+	if len(cfg.TablesCompareData) == 0 {
+		output.println("# Tables to CompareData is empty")
+		return
+	}
+
+	dataDiffTables := []string{}
+	var details []*tableDataDiff
+	sc.dataDiffs = nil
+
+	for _, table := range sc.SourceDb.GetTableNames() {
+		if !cfg.CheckMatchCompareDataTables(table) {
+			continue
+		}
+		td := sc.compareTableData(table, cfg.destTableName(table), cfg.dataCompareOption(table))
+		if td.hasDiff() {
+			dataDiffTables = append(dataDiffTables, table)
+			details = append(details, td)
+		}
+	}
+
+	if len(dataDiffTables) == 0 {
+		output.println("# no data of tables in difference")
+	} else {
+		output.println("# !!!!! data diff tables: ", strings.Join(dataDiffTables, ", "))
+		for _, td := range details {
+			output.println(td)
+		}
+	}
+	sc.dataDiffs = details
+	output.addDataDiff(dataDiffTables, details)
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = "`" + col + "`"
+	}
+	return strings.Join(quoted, ",")
+}
+
+// scanStrings 将当前行的 n 列读取为字符串，NULL 读取为空字符串
+func scanStrings(rows *sql.Rows, n int) []string {
+	values := make([]sql.NullString, n)
+	valuePtrs := make([]any, n)
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	if err := rows.Scan(valuePtrs...); err != nil {
+		log.Fatalln("scan row failed:", err)
+	}
+	strs := make([]string, n)
+	for i, v := range values {
+		strs[i] = v.String
+	}
+	return strs
+}
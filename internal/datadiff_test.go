@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_dataDiffer_rangeWhere(t *testing.T) {
+	dd := &dataDiffer{
+		result: &tableDataDiff{
+			Table:   "sys_item",
+			Keys:    []string{"id", "lang"},
+			columns: []string{"id", "lang", "name"},
+		},
+	}
+	where, args := dd.rangeWhere(nil, nil)
+	require.Equal(t, "1=1", where)
+	require.Empty(t, args)
+
+	where, args = dd.rangeWhere([]string{"10", "en"}, []string{"20", "zh"})
+	require.Equal(t, "1=1 AND (`id`,`lang`) > (?,?) AND (`id`,`lang`) <= (?,?)", where)
+	require.Equal(t, []any{"10", "en", "20", "zh"}, args)
+
+	require.Equal(t, "CRC32(CONCAT_WS('#', `id`, ISNULL(`id`), `lang`, ISNULL(`lang`), `name`, ISNULL(`name`)))", dd.rowHashExpr())
+
+	dd.result.option = &DataCompareOption{Where: "deleted = 0"}
+	where, args = dd.rangeWhere(nil, []string{"20", "zh"})
+	require.Equal(t, "(deleted = 0) AND (`id`,`lang`) <= (?,?)", where)
+	require.Equal(t, []any{"20", "zh"}, args)
+}
+
+func Test_missingColumns(t *testing.T) {
+	src := []string{"id", "code", "name"}
+	dst := []string{"id", "name"}
+	require.Empty(t, missingColumns(nil, src, dst))
+	require.Empty(t, missingColumns([]string{"id"}, src, dst))
+	require.Equal(t, []string{"code", "lang"}, missingColumns([]string{"code", "lang"}, src, dst))
+}
+
+func Test_tableDataDiff_String(t *testing.T) {
+	td := &tableDataDiff{
+		Table:    "sys_item",
+		Keys:     []string{"id"},
+		Inserted: [][]string{{"3"}, {"4"}},
+		Deleted:  [][]string{{"9"}},
+	}
+	require.True(t, td.hasDiff())
+	require.Equal(t, "# table `sys_item`: inserted 2, updated 0, deleted 1, key (id)\n#   inserted: (3) (4)\n#   deleted: (9)", td.String())
+
+	require.False(t, (&tableDataDiff{Table: "sys_item"}).hasDiff())
+}
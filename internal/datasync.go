@@ -0,0 +1,250 @@
+package internal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// dataSyncFetchSize 按主键从源库读取数据时每次读取的行数
+	dataSyncFetchSize = 500
+
+	// dataSyncBatchSize 同步数据时每个事务执行的语句数
+	dataSyncBatchSize = 500
+)
+
+// dataSyncRecord 一张表的数据同步情况
+type dataSyncRecord struct {
+	Kind    string `json:"kind"`
+	Schema  string `json:"schema"`
+	Table   string `json:"table"`
+	Inserts int    `json:"inserts"`
+	Updates int    `json:"updates"`
+	Deletes int    `json:"deletes"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// sqlLiteral 将字段值转换为 sql 字面量，非 utf8 的二进制数据使用十六进制
+func sqlLiteral(val []byte) string {
+	if val == nil {
+		return "NULL"
+	}
+	if !utf8.Valid(val) {
+		return "0x" + hex.EncodeToString(val)
+	}
+	var buf strings.Builder
+	buf.WriteByte('\'')
+	for _, r := range string(val) {
+		switch r {
+		case 0:
+			buf.WriteString(`\0`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\x1a':
+			buf.WriteString(`\Z`)
+		case '\'':
+			buf.WriteString(`\'`)
+		case '\\':
+			buf.WriteString(`\\`)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('\'')
+	return buf.String()
+}
+
+// keyWhere 按主键定位一行的条件
+func keyWhere(keys []string, vals []string) string {
+	conds := make([]string, len(keys))
+	for i, key := range keys {
+		conds[i] = fmt.Sprintf("`%s` = %s", key, sqlLiteral([]byte(vals[i])))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// fetchRows 按主键从源库读取整行数据，返回 主键 => 行
+func (sc *SchemaSync) fetchRows(td *tableDataDiff, keys [][]string) map[string][][]byte {
+	rows := make(map[string][][]byte)
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?,", len(td.Keys)), ",") + ")"
+	for start := 0; start < len(keys); start += dataSyncFetchSize {
+		batch := keys[start:min(start+dataSyncFetchSize, len(keys))]
+		var args []any
+		for _, key := range batch {
+			for _, v := range key {
+				args = append(args, v)
+			}
+		}
+		query := fmt.Sprintf("SELECT %s FROM `%s` WHERE (%s) IN (%s)",
+			quoteColumns(td.allColumns), td.Table, quoteColumns(td.Keys),
+			strings.TrimSuffix(strings.Repeat(placeholder+",", len(batch)), ","))
+		sc.scanRows(td, query, args, func(key string, row [][]byte) {
+			rows[key] = row
+		})
+	}
+	return rows
+}
+
+// scanRows 执行查询，回调每行数据及其主键
+func (sc *SchemaSync) scanRows(td *tableDataDiff, query string, args []any, fn func(key string, row [][]byte)) {
+	rs, err := sc.SourceDb.Query(query, args...)
+	if err != nil {
+		log.Fatalln("fetch rows failed:", query, err)
+	}
+	defer rs.Close()
+	for rs.Next() {
+		row := make([][]byte, len(td.allColumns))
+		ptrs := make([]any, len(td.allColumns))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rs.Scan(ptrs...); err != nil {
+			log.Fatalln("fetch rows failed when scan:", err)
+		}
+		key := make([]string, len(td.Keys))
+		for i, k := range td.Keys {
+			key[i] = string(row[indexOf(td.allColumns, k)])
+		}
+		fn(strings.Join(key, "\x00"), row)
+	}
+}
+
+func indexOf(strs []string, str string) int {
+	for i, v := range strs {
+		if v == str {
+			return i
+		}
+	}
+	return -1
+}
+
+func (sc *SchemaSync) insertSQL(td *tableDataDiff, row [][]byte) string {
+	vals := make([]string, len(row))
+	for i, v := range row {
+		vals[i] = sqlLiteral(v)
+	}
+	return fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)", td.destTable, quoteColumns(td.allColumns), strings.Join(vals, ", "))
+}
+
+// updateSQL 只修改参与对比的字段，忽略的字段保留目标库的值
+func (sc *SchemaSync) updateSQL(td *tableDataDiff, key []string, row [][]byte) string {
+	var sets []string
+	for i, col := range td.allColumns {
+		if inStringSlice(col, td.Keys) || !inStringSlice(col, td.columns) {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("`%s` = %s", col, sqlLiteral(row[i])))
+	}
+	return fmt.Sprintf("UPDATE `%s` SET %s WHERE %s", td.destTable, strings.Join(sets, ", "), keyWhere(td.Keys, key))
+}
+
+// getDataSyncSQL 生成使目标库数据与源库一致的语句，先删除再修改最后新增，drop 时才删除目标库多余的行
+func (sc *SchemaSync) getDataSyncSQL(td *tableDataDiff, cfg *Config) (sqls []string, rec *dataSyncRecord) {
+	rec = &dataSyncRecord{Table: td.Table}
+
+	if td.destMissing {
+		// 目标库的表由结构同步新建，源库的数据全部插入
+		if td.option != nil {
+			td.Keys = td.option.KeyColumns
+		}
+		if len(td.Keys) == 0 {
+			td.Keys = sc.SourceDb.GetPrimaryKeys(td.Table)
+		}
+		td.allColumns = sc.SourceDb.GetColumnNames(td.Table)
+		if missing := missingColumns(td.Keys, td.allColumns); len(missing) > 0 {
+			log.Println("key_columns of", td.Table, "not found in source:", strings.Join(missing, ","))
+			return nil, rec
+		}
+		if len(td.Keys) == 0 {
+			return nil, rec
+		}
+		query := fmt.Sprintf("SELECT %s FROM `%s` WHERE %s ORDER BY %s",
+			quoteColumns(td.allColumns), td.Table, td.where(), quoteColumns(td.Keys))
+		sc.scanRows(td, query, nil, func(_ string, row [][]byte) {
+			sqls = append(sqls, sc.insertSQL(td, row))
+			rec.Inserts++
+		})
+		return sqls, rec
+	}
+
+	if cfg.Drop {
+		for _, key := range td.Deleted {
+			sqls = append(sqls, fmt.Sprintf("DELETE FROM `%s` WHERE %s", td.destTable, keyWhere(td.Keys, key)))
+			rec.Deletes++
+		}
+	}
+	rows := sc.fetchRows(td, td.Updated)
+	for _, key := range td.Updated {
+		if row, has := rows[strings.Join(key, "\x00")]; has {
+			sqls = append(sqls, sc.updateSQL(td, key, row))
+			rec.Updates++
+		}
+	}
+	rows = sc.fetchRows(td, td.Inserted)
+	for _, key := range td.Inserted {
+		if row, has := rows[strings.Join(key, "\x00")]; has {
+			sqls = append(sqls, sc.insertSQL(td, row))
+			rec.Inserts++
+		}
+	}
+	return sqls, rec
+}
+
+// execDataSQL 分批在事务中执行数据同步语句
+func (sc *SchemaSync) execDataSQL(sqls []string) error {
+	var firstErr error
+	for start := 0; start < len(sqls); start += dataSyncBatchSize {
+		batch := sqls[start:min(start+dataSyncBatchSize, len(sqls))]
+		if err := executor.exec(sc.DestDb, batch); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SyncDiffData 根据 CheckDiffData 的对比结果生成 INSERT、UPDATE、DELETE 语句，sync 时分批执行
+func (sc *SchemaSync) SyncDiffData(cfg *Config) {
+	if !cfg.SyncData {
+		return
+	}
+	for _, td := range sc.dataDiffs {
+		if !td.destMissing && len(td.Keys) == 0 {
+			output.printf("-- data of table `%s` can not be synced without primary key\n\n", td.Table)
+			continue
+		}
+		sqls, rec := sc.getDataSyncSQL(td, cfg)
+		rec.Status = "preview"
+		output.printf("-- Data : %s, inserts %d, updates %d, deletes %d\n", td.Table, rec.Inserts, rec.Updates, rec.Deletes)
+		// 删除数据的风险为 destructive
+		blocked := rec.Deletes > 0 && !cfg.riskAllowed(riskDestructive, td.Table)
+		if blocked {
+			output.printf("-- 删除数据的风险 %s 超过 -max_risk=%s，-sync 时不执行，确认后使用 -allow %s 允许\n", riskDestructive, cfg.MaxRisk, td.Table)
+		}
+		for _, sql := range sqls {
+			output.printf("%s;\n", sql)
+		}
+		output.println("")
+
+		if cfg.Sync && blocked {
+			rec.Status = "blocked"
+			log.Println("skip data sync of table", td.Table, "deletes exceed max_risk", cfg.MaxRisk)
+		} else if cfg.Sync && len(sqls) > 0 && executor.stopped {
+			rec.Status = stmtSkipped
+		} else if cfg.Sync && len(sqls) > 0 {
+			rec.Status = stmtSuccess
+			if err := sc.execDataSQL(sqls); err != nil {
+				log.Println("sync data failed, table:", td.Table, err)
+				rec.Status = "failed"
+				rec.Error = err.Error()
+				drift.execErrors++
+			}
+		}
+		output.addDataSync(rec)
+	}
+}
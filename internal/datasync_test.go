@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sqlLiteral(t *testing.T) {
+	require.Equal(t, "NULL", sqlLiteral(nil))
+	require.Equal(t, "''", sqlLiteral([]byte{}))
+	require.Equal(t, "'12'", sqlLiteral([]byte("12")))
+	require.Equal(t, `'it\'s\n\\ok'`, sqlLiteral([]byte("it's\n\\ok")))
+	require.Equal(t, "0xff00", sqlLiteral([]byte{0xff, 0x00}))
+}
+
+func TestSchemaSync_dataSyncSQL(t *testing.T) {
+	sc := &SchemaSync{}
+	td := &tableDataDiff{
+		Table:      "sys_item",
+		destTable:  "sys_item",
+		Keys:       []string{"id"},
+		columns:    []string{"id", "name", "remark"},
+		allColumns: []string{"id", "name", "remark"},
+	}
+	row := [][]byte{[]byte("3"), []byte("sword"), nil}
+	require.Equal(t, "INSERT INTO `sys_item` (`id`,`name`,`remark`) VALUES ('3', 'sword', NULL)", sc.insertSQL(td, row))
+	require.Equal(t, "UPDATE `sys_item` SET `name` = 'sword', `remark` = NULL WHERE `id` = '3'", sc.updateSQL(td, []string{"3"}, row))
+
+	// 忽略的字段新增时写入，修改时保留目标库的值
+	td.columns = []string{"id", "name"}
+	require.Equal(t, "INSERT INTO `sys_item` (`id`,`name`,`remark`) VALUES ('3', 'sword', NULL)", sc.insertSQL(td, row))
+	require.Equal(t, "UPDATE `sys_item` SET `name` = 'sword' WHERE `id` = '3'", sc.updateSQL(td, []string{"3"}, row))
+}
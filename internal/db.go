@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"strconv"
+	"strings"
 
 	_ "github.com/go-sql-driver/mysql" // mysql driver
 )
@@ -14,10 +16,25 @@ type MyDb struct {
 	Db     *sql.DB
 	dbType string
 	DbName string
+
+	// show table status 的结果，表名 => 状态
+	tableStatus map[string]*tableStatus
+
+	// snapshot 不为 nil 时从快照文件读取结构，没有数据库连接
+	snapshot *snapshotSchema
+}
+
+// tableStatus 表的行数（估算值）和数据、索引占用的空间
+type tableStatus struct {
+	Rows int64
+	Size int64
 }
 
 // NewMyDb parse dsn
 func NewMyDb(dsn string, dbname string, dsnName string, ssh string) *MyDb {
+	if isSnapshotDSN(dsn) {
+		return newSnapshotDb(dsn, dbname, dsnName)
+	}
 
 	// 匹配字符串
 	re := regexp.MustCompile(`^([^:]+):([^@]+)@([^:]+):([^/]+)$`)
@@ -47,6 +64,10 @@ func NewMyDb(dsn string, dbname string, dsnName string, ssh string) *MyDb {
 
 // GetTableNames table names
 func (db *MyDb) GetTableNames() []string {
+	if db.snapshot != nil {
+		db.tableStatus = make(map[string]*tableStatus)
+		return sortedKeys(db.snapshot.Tables)
+	}
 	rs, err := db.Query("show table status")
 	if err != nil {
 		panic("show tables failed:" + err.Error())
@@ -54,6 +75,7 @@ func (db *MyDb) GetTableNames() []string {
 	defer rs.Close()
 
 	var tables []string
+	db.tableStatus = make(map[string]*tableStatus)
 	columns, _ := rs.Columns()
 	for rs.Next() {
 		var values = make([]any, len(columns))
@@ -78,35 +100,264 @@ func (db *MyDb) GetTableNames() []string {
 		}
 		if valObj["Engine"] != nil {
 			tables = append(tables, valObj["Name"].(string))
+			db.tableStatus[valObj["Name"].(string)] = &tableStatus{
+				Rows: statusInt(valObj["Rows"]),
+				Size: statusInt(valObj["Data_length"]) + statusInt(valObj["Index_length"]),
+			}
 		}
 	}
 	return tables
 }
 
-// Get procedure names
-func (db *MyDb) GetProcedureNames() []string {
-	rs, err := db.Query(`SELECT SPECIFIC_NAME
+func statusInt(val any) int64 {
+	n, _ := strconv.ParseInt(fmt.Sprint(val), 10, 64)
+	return n
+}
+
+// GetTableStatus 表的行数和大小，使用 GetTableNames 查询的结果
+func (db *MyDb) GetTableStatus(name string) *tableStatus {
+	if db.tableStatus == nil {
+		db.GetTableNames()
+	}
+	if st, has := db.tableStatus[name]; has {
+		return st
+	}
+	return &tableStatus{}
+}
+
+// GetRoutineNames procedure, function or event names
+func (db *MyDb) GetRoutineNames(kind routineKind) []string {
+	query := `SELECT SPECIFIC_NAME
 		FROM information_schema.ROUTINES
-		WHERE ROUTINE_TYPE = 'PROCEDURE' 
-		AND ROUTINE_SCHEMA = DATABASE()`)
+		WHERE ROUTINE_TYPE = ?
+		AND ROUTINE_SCHEMA = DATABASE()`
+	if db.snapshot != nil {
+		return sortedKeys(db.snapshot.routines(kind))
+	}
+	args := []any{strings.ToUpper(string(kind))}
+	if kind == routineEvent {
+		query = `SELECT EVENT_NAME
+		FROM information_schema.EVENTS
+		WHERE EVENT_SCHEMA = DATABASE()`
+		args = nil
+	}
+	rs, err := db.Query(query, args...)
 	if err != nil {
-		panic("show procedure failed:" + err.Error())
+		panic(fmt.Sprintf("show %s failed: %s", kind, err))
 	}
 	defer rs.Close()
 
-	var procedures []string
+	var names []string
 	for rs.Next() {
 		var vname string
 		if err := rs.Scan(&vname); err != nil {
-			panic(fmt.Sprintf("get procedure failed, %s", err))
+			panic(fmt.Sprintf("get %s failed, %s", kind, err))
+		}
+		names = append(names, vname)
+	}
+	return names
+}
+
+// GetViewNames view names
+func (db *MyDb) GetViewNames() []string {
+	if db.snapshot != nil {
+		return sortedKeys(db.snapshot.Views)
+	}
+	rs, err := db.Query(`SELECT TABLE_NAME
+		FROM information_schema.VIEWS
+		WHERE TABLE_SCHEMA = DATABASE()`)
+	if err != nil {
+		panic("show views failed:" + err.Error())
+	}
+	defer rs.Close()
+
+	var views []string
+	for rs.Next() {
+		var vname string
+		if err := rs.Scan(&vname); err != nil {
+			panic(fmt.Sprintf("get view failed, %s", err))
+		}
+		views = append(views, vname)
+	}
+	return views
+}
+
+// GetViewSchema view schema
+func (db *MyDb) GetViewSchema(name string) (schema string) {
+	if db.snapshot != nil {
+		return db.snapshot.Views[name]
+	}
+	rs, err := db.Query(fmt.Sprintf("show create view `%s`", name))
+	if err != nil {
+		// 可能视图不存在
+		return
+	}
+	defer rs.Close()
+	for rs.Next() {
+		var vname, chars, coll string
+		if err := rs.Scan(&vname, &schema, &chars, &coll); err != nil {
+			panic(fmt.Sprintf("get view %s 's schema failed, %s", name, err))
+		}
+	}
+	return
+}
+
+// GetTriggerNames trigger names and their tables
+func (db *MyDb) GetTriggerNames() (names []string, tables map[string]string) {
+	if db.snapshot != nil {
+		tables = make(map[string]string)
+		for _, tr := range db.snapshot.Triggers {
+			names = append(names, tr.Name)
+			tables[tr.Name] = tr.Table
+		}
+		return
+	}
+	rs, err := db.Query(`SELECT TRIGGER_NAME, EVENT_OBJECT_TABLE
+		FROM information_schema.TRIGGERS
+		WHERE TRIGGER_SCHEMA = DATABASE()
+		ORDER BY EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER`)
+	if err != nil {
+		panic("show triggers failed:" + err.Error())
+	}
+	defer rs.Close()
+
+	tables = make(map[string]string)
+	for rs.Next() {
+		var vname, table string
+		if err := rs.Scan(&vname, &table); err != nil {
+			panic(fmt.Sprintf("get trigger failed, %s", err))
 		}
-		procedures = append(procedures, vname)
+		names = append(names, vname)
+		tables[vname] = table
 	}
-	return procedures
+	return
+}
+
+// GetTriggerSchema trigger schema
+func (db *MyDb) GetTriggerSchema(name string) (schema string) {
+	if db.snapshot != nil {
+		for _, tr := range db.snapshot.Triggers {
+			if tr.Name == name {
+				return tr.Schema
+			}
+		}
+		return
+	}
+	rs, err := db.Query(fmt.Sprintf("show create trigger `%s`", name))
+	if err != nil {
+		// 可能触发器不存在
+		return
+	}
+	defer rs.Close()
+
+	// 不同版本的 mysql 返回的列数不同，第3列为触发器定义
+	schema, err = scanShowCreate(rs, 2)
+	if err != nil {
+		panic(fmt.Sprintf("get trigger %s 's schema failed, %s", name, err))
+	}
+	return
+}
+
+// GetPrimaryKeys primary key columns of table
+func (db *MyDb) GetPrimaryKeys(table string) []string {
+	return db.queryStrings(`SELECT COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE()
+		AND TABLE_NAME = ?
+		AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY ORDINAL_POSITION`, table)
+}
+
+// GetReferencingTables 有外键引用该表的表，其他数据库中的表带库名
+func (db *MyDb) GetReferencingTables(table string) []string {
+	return db.queryStrings(`SELECT DISTINCT IF(TABLE_SCHEMA = DATABASE(), TABLE_NAME, CONCAT(TABLE_SCHEMA, '.', TABLE_NAME))
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE REFERENCED_TABLE_SCHEMA = DATABASE()
+		AND REFERENCED_TABLE_NAME = ?`, table)
+}
+
+// GetColumnNames column names of table
+func (db *MyDb) GetColumnNames(table string) []string {
+	return db.queryStrings(`SELECT COLUMN_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+		AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, table)
+}
+
+// GetKeyRange 整数主键的最小值和最大值，表为空时返回 nil
+func (db *MyDb) GetKeyRange(table string, key string) []int64 {
+	var lo, hi sql.NullInt64
+	err := db.Db.QueryRow(fmt.Sprintf("SELECT MIN(`%s`), MAX(`%s`) FROM `%s`", key, key, table)).Scan(&lo, &hi)
+	if err != nil {
+		log.Fatalln("query key range failed:", table, err)
+	}
+	if !lo.Valid {
+		return nil
+	}
+	return []int64{lo.Int64, hi.Int64}
+}
+
+// Version 数据库版本
+func (db *MyDb) Version() string {
+	var version string
+	if db.snapshot != nil {
+		return version
+	}
+	if err := db.Db.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		log.Println("query version failed:", err)
+	}
+	return version
+}
+
+// ChecksumTable checksum table
+func (db *MyDb) ChecksumTable(table string) int64 {
+	var name string
+	var checksum sql.NullInt64
+	err := db.Db.QueryRow(fmt.Sprintf("CHECKSUM TABLE `%s`", table)).Scan(&name, &checksum)
+	if err != nil {
+		log.Fatal("failed to fetch line data: ", err)
+	}
+	return checksum.Int64
+}
+
+func (db *MyDb) queryStrings(query string, args ...any) []string {
+	rs, err := db.Query(query, args...)
+	if err != nil {
+		panic(fmt.Sprintf("query failed: %s, %s", query, err))
+	}
+	defer rs.Close()
+
+	var strs []string
+	for rs.Next() {
+		var str string
+		if err := rs.Scan(&str); err != nil {
+			panic(fmt.Sprintf("query failed when scan: %s, %s", query, err))
+		}
+		strs = append(strs, str)
+	}
+	return strs
+}
+
+// IsTableEmpty table has no rows
+func (db *MyDb) IsTableEmpty(name string) bool {
+	if db.snapshot != nil {
+		// 快照中没有数据，按有数据处理
+		return false
+	}
+	rs, err := db.Query(fmt.Sprintf("select 1 from `%s` limit 1", name))
+	if err != nil {
+		panic(fmt.Sprintf("check table %s is empty failed, %s", name, err))
+	}
+	defer rs.Close()
+	return !rs.Next()
 }
 
 // GetTableSchema table schema
 func (db *MyDb) GetTableSchema(name string) (schema string) {
+	if db.snapshot != nil {
+		return db.snapshot.Tables[name]
+	}
 	rs, err := db.Query(fmt.Sprintf("show create table `%s`", name))
 	if err != nil {
 		// 可能表不存在
@@ -126,9 +377,12 @@ func (db *MyDb) GetTableSchema(name string) (schema string) {
 	return
 }
 
-// Get procedure schema
-func (db *MyDb) GetProcedureSchema(name string, showError bool) (schema string) {
-	rs, err := db.Query(fmt.Sprintf("show create PROCEDURE `%s`", name))
+// GetRoutineSchema procedure, function or event schema
+func (db *MyDb) GetRoutineSchema(kind routineKind, name string, showError bool) (schema string) {
+	if db.snapshot != nil {
+		return db.snapshot.routines(kind)[name]
+	}
+	rs, err := db.Query(fmt.Sprintf("show create %s `%s`", kind, name))
 	if err != nil {
 		if showError {
 			log.Println(err)
@@ -136,17 +390,42 @@ func (db *MyDb) GetProcedureSchema(name string, showError bool) (schema string)
 		return
 	}
 	defer rs.Close()
-	for rs.Next() {
-		var vname, sqlmode, chars, coll, dbcoll string
-		if err := rs.Scan(&vname, &sqlmode, &schema, &chars, &coll, &dbcoll); err != nil {
-			panic(fmt.Sprintf("get table %s 's schema failed, %s", name, err))
-		}
+
+	// show create event 比 procedure、function 多了 time_zone 列
+	schemaIndex := 2
+	if kind == routineEvent {
+		schemaIndex = 3
+	}
+	schema, err = scanShowCreate(rs, schemaIndex)
+	if err != nil {
+		panic(fmt.Sprintf("get %s %s 's schema failed, %s", kind, name, err))
 	}
 	return
 }
 
 // Query execute sql query
 func (db *MyDb) Query(query string, args ...any) (*sql.Rows, error) {
+	if db.snapshot != nil {
+		return nil, errSnapshotQuery
+	}
 	// log.Println("[SQL]", "["+db.dbType+"]", query, args)
 	return db.Db.Query(query, args...)
 }
+
+// scanShowCreate 读取 show create 结果中第 index 列的定义，结果的列数随 mysql 版本不同
+func scanShowCreate(rs *sql.Rows, index int) (string, error) {
+	var schema string
+	columns, _ := rs.Columns()
+	for rs.Next() {
+		values := make([]sql.RawBytes, len(columns))
+		valuePtrs := make([]any, len(columns))
+		for i := range columns {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rs.Scan(valuePtrs...); err != nil {
+			return "", err
+		}
+		schema = string(values[index])
+	}
+	return schema, nil
+}
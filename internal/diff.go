@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"strings"
+)
+
+type diffKind string
+
+const (
+	diffEqual  diffKind = "equal"
+	diffChange diffKind = "change"
+	diffDelete diffKind = "delete"
+	diffInsert diffKind = "insert"
+)
+
+// diffRow 左右对照的一行，Left 为源库，Right 为目标库
+type diffRow struct {
+	Kind  diffKind
+	Left  string
+	Right string
+}
+
+// sideBySideDiff 按行对比两段文本（基于最长公共子序列），
+// 连续的删除和新增行会合并为 change 行，便于左右对照展示
+func sideBySideDiff(left string, right string) []diffRow {
+	a := splitLines(left)
+	b := splitLines(right)
+
+	// lcs[i][j] 表示 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var rows []diffRow
+	var dels, inss []string
+	flush := func() {
+		n := max(len(dels), len(inss))
+		for k := 0; k < n; k++ {
+			row := diffRow{Kind: diffChange}
+			if k < len(dels) {
+				row.Left = dels[k]
+			} else {
+				row.Kind = diffInsert
+			}
+			if k < len(inss) {
+				row.Right = inss[k]
+			} else {
+				row.Kind = diffDelete
+			}
+			rows = append(rows, row)
+		}
+		dels, inss = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			rows = append(rows, diffRow{Kind: diffEqual, Left: a[i], Right: b[j]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			inss = append(inss, b[j])
+			j++
+		default:
+			dels = append(dels, a[i])
+			i++
+		}
+	}
+	flush()
+	return rows
+}
+
+func splitLines(str string) []string {
+	str = strings.TrimRight(strings.ReplaceAll(str, "\r\n", "\n"), "\n")
+	if len(str) == 0 {
+		return nil
+	}
+	return strings.Split(str, "\n")
+}
@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sideBySideDiff(t *testing.T) {
+	got := sideBySideDiff(testLoadFile("testdata/user_0.sql"), testLoadFile("testdata/user_1.sql"))
+	var kinds []diffKind
+	for _, row := range got {
+		kinds = append(kinds, row.Kind)
+	}
+	require.Equal(t, []diffKind{diffEqual, diffEqual, diffEqual, diffDelete, diffDelete, diffDelete, diffEqual, diffEqual}, kinds)
+	require.Equal(t, "    `register_time` timestamp NOT NULL,", got[3].Left)
+	require.Equal(t, "", got[3].Right)
+
+	got = sideBySideDiff("a\nb\nc", "a\nx\nc\nd")
+	require.Equal(t, []diffRow{
+		{Kind: diffEqual, Left: "a", Right: "a"},
+		{Kind: diffChange, Left: "b", Right: "x"},
+		{Kind: diffEqual, Left: "c", Right: "c"},
+		{Kind: diffInsert, Right: "d"},
+	}, got)
+}
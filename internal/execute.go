@@ -0,0 +1,297 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 执行出错时的处理方式
+const (
+	onErrorStop     = "stop"
+	onErrorContinue = "continue"
+)
+
+// 语句的执行状态
+const (
+	stmtSuccess    = "success"
+	stmtFailed     = "failed"
+	stmtRolledBack = "rolled_back"
+	stmtSkipped    = "skipped"
+	// 之前的运行中已成功执行，-resume 时跳过
+	stmtApplied = "applied"
+)
+
+// errExecStopped 之前的语句执行出错且 -on_error=stop，之后的语句不再执行
+var errExecStopped = errors.New("skipped after previous error, on_error=stop")
+
+// ddlReg DDL 语句会隐式提交事务，执行后无法回滚
+var ddlReg = regexp.MustCompile(`(?is)^\s*(/\*!\d*\s*)?(CREATE|ALTER|DROP|RENAME|TRUNCATE)\b`)
+
+func isDDL(sql string) bool {
+	return ddlReg.MatchString(sql)
+}
+
+// stmtResult 一条语句的执行情况
+type stmtResult struct {
+	SQL      string  `json:"sql"`
+	DDL      bool    `json:"ddl"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Affected int64   `json:"affected"`
+	Used     float64 `json:"used"`
+}
+
+// sqlExecutor 在目标库逐条执行语句并记录每条语句的结果：
+// DDL 会隐式提交，单独执行；连续的 DML 在一个事务中执行，出错且 on_error=stop 时回滚。
+// 所有语句在同一个连接中执行，SET、USE 等设置的会话状态对之后的语句有效
+type sqlExecutor struct {
+	onError string
+	stopped bool
+	results []*stmtResult
+	journal *sqlJournal
+
+	conn   *sql.Conn
+	connDb *MyDb
+}
+
+var executor = &sqlExecutor{onError: onErrorStop}
+
+// connOf 执行语句使用的连接，切换数据库时释放之前的连接
+func (e *sqlExecutor) connOf(mydb *MyDb) (*sql.Conn, error) {
+	if e.conn != nil && e.connDb == mydb {
+		return e.conn, nil
+	}
+	e.release()
+	conn, err := mydb.Db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	e.conn, e.connDb = conn, mydb
+	return conn, nil
+}
+
+// release 将执行语句的连接放回连接池
+func (e *sqlExecutor) release() {
+	if e.conn != nil {
+		_ = e.conn.Close()
+		e.conn, e.connDb = nil, nil
+	}
+}
+
+// exec 执行一组语句，返回第一个错误，on_error=stop 时出错后的语句（包括之后调用 exec 的语句）都不再执行
+func (e *sqlExecutor) exec(mydb *MyDb, sqls []string) error {
+	return e.run(mydb, sqls, false)
+}
+
+// execSequence 依次执行一组相互依赖的语句（如影子表复制），每条语句单独提交，
+// 任意一条出错时不论 on_error 如何都不再执行之后的语句，并执行 cleanup 恢复执行前的状态。
+// 出错后已恢复，-resume 时整组重新执行，不跳过其中已执行的语句
+func (e *sqlExecutor) execSequence(mydb *MyDb, sqls []string, cleanup []string) error {
+	err := e.run(mydb, sqls, true)
+	if err == nil || err == errExecStopped {
+		return err
+	}
+	log.Println("sequence aborted, cleanup:", err)
+	for _, query := range cleanup {
+		st := &stmtResult{SQL: query, DDL: isDDL(query), Status: stmtSuccess}
+		e.results = append(e.results, st)
+		conn, cerr := e.connOf(mydb)
+		if cerr == nil {
+			start := time.Now()
+			_, cerr = conn.ExecContext(context.Background(), query)
+			st.Used = time.Since(start).Seconds()
+		}
+		if cerr != nil {
+			st.Status = stmtFailed
+			st.Error = cerr.Error()
+			log.Println("exec cleanup sql failed:", query, cerr)
+		}
+	}
+	return err
+}
+
+// run 执行一组语句，sequence 时每条语句单独提交，出错后不再执行之后的语句
+func (e *sqlExecutor) run(mydb *MyDb, sqls []string, sequence bool) error {
+	ctx := context.Background()
+	plan := planHash(mydb.DbName, sqls)
+	e.journal.begin(plan, mydb.DbName, len(sqls))
+	var conn *sql.Conn
+	var firstErr error
+	var tx *sql.Tx
+	var pending []*stmtResult
+	// aborted sequence 时已有语句出错
+	aborted := false
+
+	fail := func(st *stmtResult, err error) {
+		st.Status = stmtFailed
+		st.Error = err.Error()
+		if firstErr == nil {
+			firstErr = err
+		}
+		if e.onError == onErrorStop {
+			e.stopped = true
+		}
+		if sequence {
+			aborted = true
+		}
+	}
+	rollback := func() {
+		_ = tx.Rollback()
+		for _, st := range pending {
+			if st.Status == stmtSuccess {
+				st.Status = stmtRolledBack
+			}
+		}
+		e.journal.write(plan, mydb.DbName, pending...)
+		tx, pending = nil, nil
+	}
+	commit := func() {
+		if tx == nil {
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			fail(pending[len(pending)-1], fmt.Errorf("commit failed: %w", err))
+			rollback()
+			return
+		}
+		e.journal.write(plan, mydb.DbName, pending...)
+		tx, pending = nil, nil
+	}
+
+	for _, query := range sqls {
+		st := &stmtResult{SQL: query, DDL: isDDL(query), Status: stmtSkipped}
+		e.results = append(e.results, st)
+		if e.stopped || aborted {
+			if firstErr == nil {
+				firstErr = errExecStopped
+			}
+			continue
+		}
+		if !sequence && e.journal.skip(plan, mydb.DbName, query) {
+			st.Status = stmtApplied
+			continue
+		}
+
+		var ret sql.Result
+		var err error
+		if conn == nil {
+			if conn, err = e.connOf(mydb); err != nil {
+				fail(st, err)
+				continue
+			}
+		}
+		start := time.Now()
+		if st.DDL {
+			commit()
+			if e.stopped || aborted {
+				continue
+			}
+			ret, err = conn.ExecContext(ctx, query)
+		} else {
+			if tx == nil {
+				if tx, err = conn.BeginTx(ctx, nil); err != nil {
+					tx = nil
+					fail(st, err)
+					continue
+				}
+			}
+			pending = append(pending, st)
+			ret, err = tx.Exec(query)
+		}
+		st.Used = time.Since(start).Seconds()
+
+		if err != nil {
+			fail(st, err)
+			log.Println("exec sql failed:", query, err)
+			if st.DDL {
+				e.journal.write(plan, mydb.DbName, st)
+			} else if e.stopped || aborted {
+				rollback()
+			}
+			continue
+		}
+		st.Status = stmtSuccess
+		st.Affected, _ = ret.RowsAffected()
+		if st.DDL {
+			e.journal.write(plan, mydb.DbName, st)
+		} else if sequence {
+			commit()
+		}
+	}
+	commit()
+	return firstErr
+}
+
+// clean 所有语句都已成功执行（或之前已执行）
+func (e *sqlExecutor) clean() bool {
+	if e.stopped {
+		return false
+	}
+	for _, st := range e.results {
+		if st.Status != stmtSuccess && st.Status != stmtApplied {
+			return false
+		}
+	}
+	return true
+}
+
+// report 执行报告，每条语句一行，以 sql 注释的形式输出
+func (e *sqlExecutor) report() string {
+	counts := make(map[string]int)
+	ddlApplied := 0
+	for _, st := range e.results {
+		counts[st.Status]++
+		if st.DDL && st.Status == stmtSuccess {
+			ddlApplied++
+		}
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "-- execute report: %d statements, success %d, failed %d, rolled back %d, skipped %d, applied before %d\n",
+		len(e.results), counts[stmtSuccess], counts[stmtFailed], counts[stmtRolledBack], counts[stmtSkipped], counts[stmtApplied])
+	for _, st := range e.results {
+		kind := "dml"
+		if st.DDL {
+			kind = "ddl"
+		}
+		line, _, _ := strings.Cut(st.SQL, "\n")
+		if runes := []rune(line); len(runes) > 100 {
+			line = string(runes[:100]) + "..."
+		}
+		fmt.Fprintf(&buf, "-- [%s] %s %.3fs rows=%d: %s\n", st.Status, kind, st.Used, st.Affected, line)
+		if len(st.Error) > 0 {
+			fmt.Fprintf(&buf, "--     error: %s\n", st.Error)
+		}
+	}
+	if counts[stmtFailed] > 0 && ddlApplied > 0 {
+		fmt.Fprintf(&buf, "-- %d ddl statements have been applied and can not be rolled back\n", ddlApplied)
+	}
+	return buf.String()
+}
+
+// ExecReport 输出本次运行在目标库执行的所有语句的结果
+func ExecReport() {
+	executor.release()
+	if len(executor.results) > 0 && executor.clean() {
+		executor.journal.finish()
+		executor.journal = nil
+	}
+	if len(executor.results) == 0 {
+		return
+	}
+	switch output.format {
+	case formatText:
+		fmt.Print(executor.report())
+	case formatNDJSON:
+		output.writeRecord(&execRecord{Kind: recordExec, Statements: executor.results})
+	case formatJSON:
+		if output.run != nil {
+			output.run.Executed = executor.results
+		}
+	}
+}
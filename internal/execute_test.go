@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver 记录执行的语句，healed 为 false 时包含 fail 的语句执行失败
+type fakeDriver struct {
+	log    []string
+	opens  int
+	healed bool
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	d.opens++
+	return &fakeConn{d}, nil
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.d.log = append(c.d.log, "BEGIN")
+	return c, nil
+}
+func (c *fakeConn) Commit() error {
+	c.d.log = append(c.d.log, "COMMIT")
+	return nil
+}
+func (c *fakeConn) Rollback() error {
+	c.d.log = append(c.d.log, "ROLLBACK")
+	return nil
+}
+func (c *fakeConn) Exec(query string, _ []driver.Value) (driver.Result, error) {
+	if strings.Contains(query, "fail") && !c.d.healed {
+		return nil, errors.New("exec failed")
+	}
+	c.d.log = append(c.d.log, query)
+	return driver.RowsAffected(1), nil
+}
+
+func newFakeDB(name string) (*fakeDriver, *MyDb) {
+	d := &fakeDriver{}
+	sql.Register(name, d)
+	db, _ := sql.Open(name, "")
+	db.SetMaxOpenConns(1)
+	return d, &MyDb{Db: db, DbName: "test"}
+}
+
+func Test_isDDL(t *testing.T) {
+	require.True(t, isDDL("ALTER TABLE `user` ADD `a` int"))
+	require.True(t, isDDL("\n create table t (id int)"))
+	require.True(t, isDDL("/*!50003 CREATE*/ /*!50017 DEFINER=`root`@`%`*/ TRIGGER tr"))
+	require.True(t, isDDL("DROP\nVIEW v"))
+	require.False(t, isDDL("INSERT INTO `user` VALUES (1)"))
+	require.False(t, isDDL("UPDATE t SET created = 1"))
+}
+
+func Test_sqlExecutor_exec(t *testing.T) {
+	d, db := newFakeDB("fake_exec_stop")
+	e := &sqlExecutor{onError: onErrorStop}
+	err := e.exec(db, []string{"ALTER TABLE a ADD x int", "INSERT 1", "INSERT fail", "ALTER TABLE b ADD y int"})
+	require.EqualError(t, err, "exec failed")
+	require.Equal(t, []string{"ALTER TABLE a ADD x int", "BEGIN", "INSERT 1", "ROLLBACK"}, d.log)
+	var status []string
+	for _, st := range e.results {
+		status = append(status, st.Status)
+	}
+	require.Equal(t, []string{stmtSuccess, stmtRolledBack, stmtFailed, stmtSkipped}, status)
+	require.Equal(t, errExecStopped, e.exec(db, []string{"ALTER TABLE c ADD z int"}))
+	require.Contains(t, e.report(), "-- 1 ddl statements have been applied and can not be rolled back")
+	e.release()
+
+	d, db = newFakeDB("fake_exec_continue")
+	e = &sqlExecutor{onError: onErrorContinue}
+	err = e.exec(db, []string{"INSERT 1", "INSERT fail", "INSERT 2", "ALTER TABLE b ADD y int"})
+	require.EqualError(t, err, "exec failed")
+	require.Equal(t, []string{"BEGIN", "INSERT 1", "INSERT 2", "COMMIT", "ALTER TABLE b ADD y int"}, d.log)
+	require.Equal(t, int64(1), e.results[3].Affected)
+	require.Equal(t, stmtSuccess, e.results[3].Status)
+	e.release()
+}
+
+func Test_sqlExecutor_sameConn(t *testing.T) {
+	d, db := newFakeDB("fake_exec_conn")
+	// 连接池不保留空闲连接，每次从连接池执行都会新建连接
+	db.Db.SetMaxIdleConns(0)
+	e := &sqlExecutor{onError: onErrorStop}
+	require.NoError(t, e.exec(db, []string{"SET FOREIGN_KEY_CHECKS=0", "ALTER TABLE a ADD x int", "INSERT 1"}))
+	require.NoError(t, e.exec(db, []string{"ALTER TABLE b ADD y int"}))
+	require.Equal(t, []string{"BEGIN", "SET FOREIGN_KEY_CHECKS=0", "COMMIT", "ALTER TABLE a ADD x int", "BEGIN", "INSERT 1", "COMMIT", "ALTER TABLE b ADD y int"}, d.log)
+	require.Equal(t, 1, d.opens)
+	e.release()
+}
+
+func Test_sqlExecutor_execSequence(t *testing.T) {
+	d, db := newFakeDB("fake_exec_sequence")
+	e := &sqlExecutor{onError: onErrorContinue}
+	sqls := []string{"CREATE TABLE `_t_new` LIKE `t`", "INSERT 1", "INSERT fail", "INSERT 3", "RENAME TABLE `t` TO `_t_old`, `_t_new` TO `t`"}
+	cleanup := []string{"DROP TRIGGER IF EXISTS `_t_osc_ins`", "DROP TABLE IF EXISTS `_t_new`"}
+	err := e.execSequence(db, sqls, cleanup)
+	require.EqualError(t, err, "exec failed")
+	// 每条语句单独提交，出错后即使 on_error=continue 也不再执行 RENAME
+	require.Equal(t, []string{"CREATE TABLE `_t_new` LIKE `t`", "BEGIN", "INSERT 1", "COMMIT", "BEGIN", "ROLLBACK",
+		"DROP TRIGGER IF EXISTS `_t_osc_ins`", "DROP TABLE IF EXISTS `_t_new`"}, d.log)
+	var status []string
+	for _, st := range e.results {
+		status = append(status, st.Status)
+	}
+	require.Equal(t, []string{stmtSuccess, stmtSuccess, stmtFailed, stmtSkipped, stmtSkipped, stmtSuccess, stmtSuccess}, status)
+
+	// on_error=continue 时之后的语句继续执行
+	d.log = nil
+	require.NoError(t, e.exec(db, []string{"ALTER TABLE b ADD y int"}))
+	require.Equal(t, []string{"ALTER TABLE b ADD y int"}, d.log)
+	e.release()
+}
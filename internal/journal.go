@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"time"
+)
+
+// journalPlan 执行计划的记录，每次执行一组语句前写入，包含语句数量
+const journalPlan = "plan"
+
+// journalEntry 执行日志中的一条记录，每行一个 json
+type journalEntry struct {
+	// Plan 所属的执行计划，为一次执行的所有语句的 hash
+	Plan   string `json:"plan"`
+	Hash   string `json:"hash,omitempty"`
+	Schema string `json:"schema"`
+	Status string `json:"status"`
+	Count  int    `json:"count,omitempty"`
+	Time   string `json:"time"`
+	SQL    string `json:"sql,omitempty"`
+}
+
+// sqlJournal 记录在目标库执行过的语句，-resume 时跳过未完成的相同执行计划中已成功执行的语句，
+// 运行正常结束时日志文件重命名为 .done，之后的运行不会再跳过其中的语句
+type sqlJournal struct {
+	path string
+	file *os.File
+	// 未完成的执行计划 => 已成功执行的语句 hash => 次数，同一条语句执行多次时按次数跳过
+	applied map[string]map[string]int
+}
+
+// stmtHash 语句的 hash，包含目标数据库名
+func stmtHash(schema string, sql string) string {
+	sum := sha256.Sum256([]byte(schema + "\x00" + sql))
+	return hex.EncodeToString(sum[:])
+}
+
+// planHash 执行计划的 hash，包含目标数据库名和所有语句
+func planHash(schema string, sqls []string) string {
+	h := sha256.New()
+	h.Write([]byte(schema))
+	for _, sql := range sqls {
+		h.Write([]byte("\x00" + sql))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// openJournal 以追加的方式打开执行日志，resume 时读取未完成的执行计划中已成功执行的语句
+func openJournal(path string, resume bool) *sqlJournal {
+	j := &sqlJournal{path: path, applied: make(map[string]map[string]int)}
+	if resume {
+		if err := j.load(path); err != nil {
+			log.Fatalln("load journal failed:", path, err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		log.Fatalln("open journal failed:", path, err)
+	}
+	// 进程中断时最后一行可能不完整，换行后再追加
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			_, _ = file.Write([]byte{'\n'})
+		}
+	}
+	j.file = file
+	return j
+}
+
+func (j *sqlJournal) load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	counts := make(map[string]int)
+	succeeded := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		// 进程中断时最后一行可能不完整，忽略
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Println("skip broken journal line:", err)
+			continue
+		}
+		switch entry.Status {
+		case journalPlan:
+			counts[entry.Plan] = entry.Count
+		case stmtSuccess:
+			if j.applied[entry.Plan] == nil {
+				j.applied[entry.Plan] = make(map[string]int)
+			}
+			j.applied[entry.Plan][entry.Hash]++
+			succeeded[entry.Plan]++
+		}
+	}
+	// 所有语句都已成功执行的计划已完成，再次出现时需要重新执行
+	for plan := range j.applied {
+		if succeeded[plan] >= counts[plan] {
+			delete(j.applied, plan)
+		}
+	}
+	return scanner.Err()
+}
+
+// begin 开始执行一组语句，记录执行计划
+func (j *sqlJournal) begin(plan string, schema string, count int) {
+	if j == nil {
+		return
+	}
+	j.append(&journalEntry{Plan: plan, Schema: schema, Status: journalPlan, Count: count})
+}
+
+// skip 语句在之前未完成的相同执行计划中已成功执行
+func (j *sqlJournal) skip(plan string, schema string, sql string) bool {
+	if j == nil {
+		return false
+	}
+	hash := stmtHash(schema, sql)
+	if j.applied[plan][hash] == 0 {
+		return false
+	}
+	j.applied[plan][hash]--
+	return true
+}
+
+// write 记录语句的执行结果，立即写入文件以便进程中断后恢复
+func (j *sqlJournal) write(plan string, schema string, sts ...*stmtResult) {
+	if j == nil || len(sts) == 0 {
+		return
+	}
+	entries := make([]*journalEntry, 0, len(sts))
+	for _, st := range sts {
+		entries = append(entries, &journalEntry{
+			Plan:   plan,
+			Hash:   stmtHash(schema, st.SQL),
+			Schema: schema,
+			Status: st.Status,
+			SQL:    st.SQL,
+		})
+	}
+	j.append(entries...)
+}
+
+func (j *sqlJournal) append(entries ...*journalEntry) {
+	var buf []byte
+	now := time.Now().Format(timeFormatStd)
+	for _, entry := range entries {
+		entry.Time = now
+		bs, _ := json.Marshal(entry)
+		buf = append(append(buf, bs...), '\n')
+	}
+	if _, err := j.file.Write(buf); err != nil {
+		log.Fatalln("write journal failed:", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		log.Fatalln("sync journal failed:", err)
+	}
+}
+
+// finish 运行正常结束，日志文件重命名为 .done，之后使用同一个日志文件 -resume 时不会跳过其中的语句
+func (j *sqlJournal) finish() {
+	if j == nil {
+		return
+	}
+	_ = j.file.Close()
+	if err := os.Rename(j.path, j.path+".done"); err != nil {
+		log.Println("rotate journal failed:", err)
+	}
+	j.file = nil
+}
@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sqlJournal_resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.journal")
+
+	d, db := newFakeDB("fake_journal")
+	e := &sqlExecutor{onError: onErrorStop, journal: openJournal(path, false)}
+	err := e.exec(db, []string{"ALTER TABLE a ADD x int", "INSERT 1", "INSERT 1", "ALTER TABLE fail", "ALTER TABLE b ADD y int"})
+	require.Error(t, err)
+	require.Equal(t, []string{"ALTER TABLE a ADD x int", "BEGIN", "INSERT 1", "INSERT 1", "COMMIT"}, d.log)
+	e.release()
+
+	// 进程中断时最后一行可能不完整
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	_, _ = f.WriteString(`{"hash":"`)
+	f.Close()
+
+	// 同一个执行计划继续执行，跳过已成功执行的语句
+	sqls := []string{"ALTER TABLE a ADD x int", "INSERT 1", "INSERT 1", "ALTER TABLE fail", "ALTER TABLE b ADD y int"}
+	plan := planHash("test", sqls)
+	j := openJournal(path, true)
+	require.False(t, j.skip(planHash("test", sqls[:1]), "test", "ALTER TABLE a ADD x int"), "other plan")
+	require.False(t, j.skip(plan, "other", "INSERT 1"), "other schema")
+
+	d.log = nil
+	d.healed = true
+	e = &sqlExecutor{onError: onErrorStop, journal: j}
+	require.NoError(t, e.exec(db, sqls))
+	require.Equal(t, []string{"ALTER TABLE fail", "ALTER TABLE b ADD y int"}, d.log)
+	var status []string
+	for _, st := range e.results {
+		status = append(status, st.Status)
+	}
+	require.Equal(t, []string{stmtApplied, stmtApplied, stmtApplied, stmtSuccess, stmtSuccess}, status)
+	e.release()
+
+	// 已完成的执行计划再次出现时重新执行
+	require.False(t, openJournal(path, true).skip(plan, "test", "ALTER TABLE a ADD x int"))
+
+	// 正常结束时日志重命名为 .done
+	require.True(t, e.clean())
+	e.journal.finish()
+	_, err = os.Stat(path + ".done")
+	require.NoError(t, err)
+	require.Empty(t, openJournal(path, true).applied)
+}
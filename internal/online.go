@@ -0,0 +1,288 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 大表的在线变更方式
+const (
+	onlineNone    = ""
+	onlineInstant = "INSTANT"
+	onlineInplace = "INPLACE"
+	// onlineShadow 影子表复制：新建 _表名_new 并变更，触发器同步写入，分块复制数据后 RENAME TABLE 替换
+	onlineShadow = "shadow_copy"
+)
+
+// OnlineOption 大表在线变更的设置，行数或大小任一达到阈值时使用在线变更
+type OnlineOption struct {
+	// MinRows 表的行数（估算值），默认 1000000
+	MinRows int64 `json:"min_rows"`
+
+	// MinSizeMB 表的数据和索引大小（MB），默认 1024
+	MinSizeMB int64 `json:"min_size_mb"`
+
+	// ChunkSize 影子表复制数据时每次复制的主键范围，默认 10000
+	ChunkSize int64 `json:"chunk_size"`
+}
+
+func (cfg *Config) onlineOption() *OnlineOption {
+	opt := &OnlineOption{MinRows: 1000000, MinSizeMB: 1024, ChunkSize: 10000}
+	if cfg.OnlineOption != nil {
+		if cfg.OnlineOption.MinRows > 0 {
+			opt.MinRows = cfg.OnlineOption.MinRows
+		}
+		if cfg.OnlineOption.MinSizeMB > 0 {
+			opt.MinSizeMB = cfg.OnlineOption.MinSizeMB
+		}
+		if cfg.OnlineOption.ChunkSize > 0 {
+			opt.ChunkSize = cfg.OnlineOption.ChunkSize
+		}
+	}
+	return opt
+}
+
+var (
+	columnDefaultReg = regexp.MustCompile(`\sDEFAULT\s+('([^'\\]|\\.|'')*'|\S+)`)
+	columnCommentReg = regexp.MustCompile(`\sCOMMENT\s+'([^'\\]|\\.|'')*'`)
+	intColumnReg     = regexp.MustCompile(`(?i)^\s*(tiny|small|medium|big)?int\b`)
+	mysqlVersionReg  = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+)
+
+// onlyDefaultChanged 字段定义只有默认值或注释不同
+func onlyDefaultChanged(ch *schemaChange) bool {
+	strip := func(def string) string {
+		def = columnDefaultReg.ReplaceAllString(def, "")
+		return columnCommentReg.ReplaceAllString(def, "")
+	}
+	return strip(ch.Before) == strip(ch.After)
+}
+
+// supportInstant 目标库是否支持 ALGORITHM=INSTANT 的任意位置加字段、删字段（MySQL 8.0.29+）
+func supportInstant(version string) bool {
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return false
+	}
+	m := mysqlVersionReg.FindStringSubmatch(version)
+	if m == nil {
+		return false
+	}
+	var v [3]int
+	for i := range v {
+		v[i], _ = strconv.Atoi(m[i+1])
+	}
+	return v[0] > 8 || (v[0] == 8 && (v[1] > 0 || v[2] >= 29))
+}
+
+// onlineAlgorithm 根据变更项选择在线变更方式：
+// 全部可以 INSTANT 时使用 INSTANT，不需要重建表或只是重建表时使用 INPLACE, LOCK=NONE，
+// 修改字段类型、主键、表选项、全文索引等需要复制数据时使用影子表，新增外键不支持在线变更
+func onlineAlgorithm(sd *TableAlterData, instant bool) string {
+	allInstant := instant
+	shadow := false
+	for _, ch := range sd.Changes {
+		switch ch.Kind {
+		case changeColumnAdd, changeColumnDrop, changeColumnRename:
+		case changeColumnChange:
+			if !onlyDefaultChanged(ch) {
+				shadow = true
+			}
+		case changeIndexAdd, changeIndexReplace, changeIndexDrop:
+			allInstant = false
+			def := ch.After
+			if len(def) == 0 {
+				def = ch.Before
+			}
+			if strings.HasPrefix(def, "PRIMARY") || strings.HasPrefix(def, "FULLTEXT") || strings.HasPrefix(def, "SPATIAL") {
+				shadow = true
+			}
+		case changeForeignDrop:
+			allInstant = false
+		case changeTableOptions:
+			shadow = true
+		default:
+			return onlineNone
+		}
+	}
+	switch {
+	case shadow:
+		return onlineShadow
+	case allInstant:
+		return onlineInstant
+	default:
+		return onlineInplace
+	}
+}
+
+// withAlgorithm 在 ALTER 语句后加上 ALGORITHM、LOCK 选项
+func withAlgorithm(sql string, algorithm string) string {
+	option := "ALGORITHM=INSTANT"
+	if algorithm == onlineInplace {
+		option = "ALGORITHM=INPLACE, LOCK=NONE"
+	}
+	return strings.TrimRight(sql, ";") + ",\n" + option + ";"
+}
+
+// alterClauses 所有变更项的 ALTER TABLE 子句
+func alterClauses(sd *TableAlterData) string {
+	var clauses []string
+	for _, ch := range sd.Changes {
+		clauses = append(clauses, ch.clauses()...)
+	}
+	return strings.Join(clauses, ",\n")
+}
+
+// shadowColumns 复制数据时原表字段在新表中对应的字段，删除的字段不复制，重命名的字段使用新名字
+func shadowColumns(sd *TableAlterData) (newColumns []string, oldColumns []string) {
+	renameTo := make(map[string]string)
+	dropped := make(map[string]bool)
+	for _, ch := range sd.Changes {
+		switch ch.Kind {
+		case changeColumnRename:
+			renameTo[ch.From] = ch.Name
+		case changeColumnDrop:
+			dropped[ch.Name] = true
+		}
+	}
+	for _, name := range sd.SchemaDiff.Dest.GetFieldNames() {
+		if dropped[name] {
+			continue
+		}
+		newName := name
+		if to, has := renameTo[name]; has {
+			newName = to
+		}
+		newColumns = append(newColumns, newName)
+		oldColumns = append(oldColumns, name)
+	}
+	return newColumns, oldColumns
+}
+
+// shadowCopySQL 影子表复制的语句，keyRange 为原表整数主键的最小、最大值，nil 表示表为空
+func shadowCopySQL(sd *TableAlterData, key string, keyRange []int64, chunkSize int64) []string {
+	table := sd.Table
+	newTable := "_" + table + "_new"
+	oldTable := "_" + table + "_old"
+	trigger := func(event string) string {
+		return fmt.Sprintf("_%s_osc_%s", table, event)
+	}
+	newColumns, oldColumns := shadowColumns(sd)
+	var newValues []string
+	for _, col := range oldColumns {
+		newValues = append(newValues, "NEW.`"+col+"`")
+	}
+	replaceSQL := fmt.Sprintf("REPLACE INTO `%s` (%s) VALUES (%s)", newTable, quoteColumns(newColumns), strings.Join(newValues, ", "))
+	deleteSQL := fmt.Sprintf("DELETE FROM `%s` WHERE `%s` = OLD.`%s`", newTable, key, key)
+
+	sqls := []string{
+		fmt.Sprintf("CREATE TABLE `%s` LIKE `%s`;", newTable, table),
+		fmt.Sprintf("ALTER TABLE `%s`\n%s;", newTable, alterClauses(sd)),
+		fmt.Sprintf("CREATE TRIGGER `%s` AFTER DELETE ON `%s` FOR EACH ROW %s;", trigger("del"), table, deleteSQL),
+		fmt.Sprintf("CREATE TRIGGER `%s` AFTER UPDATE ON `%s` FOR EACH ROW %s;", trigger("upd_del"), table, deleteSQL),
+		fmt.Sprintf("CREATE TRIGGER `%s` AFTER UPDATE ON `%s` FOR EACH ROW FOLLOWS `%s` %s;", trigger("upd"), table, trigger("upd_del"), replaceSQL),
+		fmt.Sprintf("CREATE TRIGGER `%s` AFTER INSERT ON `%s` FOR EACH ROW %s;", trigger("ins"), table, replaceSQL),
+	}
+
+	// 分块复制，最后一块不设上界，包含生成语句后新增的行；
+	// 不使用 INSERT IGNORE，转换失败时和直接 ALTER 一样报错，已由触发器复制的行按主键跳过
+	copySQL := fmt.Sprintf("INSERT INTO `%s` (%s) SELECT %s FROM `%s`", newTable, quoteColumns(newColumns), quoteColumns(oldColumns), table)
+	onDup := fmt.Sprintf(" ON DUPLICATE KEY UPDATE `%s`.`%s` = `%s`.`%s`;", newTable, key, newTable, key)
+	if keyRange == nil {
+		sqls = append(sqls, copySQL+" LOCK IN SHARE MODE"+onDup)
+	} else {
+		lo := keyRange[0]
+		for ; lo+chunkSize <= keyRange[1]; lo += chunkSize {
+			sqls = append(sqls, fmt.Sprintf("%s WHERE `%s` >= %d AND `%s` < %d LOCK IN SHARE MODE%s", copySQL, key, lo, key, lo+chunkSize, onDup))
+		}
+		sqls = append(sqls, fmt.Sprintf("%s WHERE `%s` >= %d LOCK IN SHARE MODE%s", copySQL, key, lo, onDup))
+	}
+
+	// 行数不一致时子查询返回多行而报错，中止替换并清理影子表
+	sqls = append(sqls, fmt.Sprintf("DO IF((SELECT COUNT(*) FROM `%s`) = (SELECT COUNT(*) FROM `%s`), 0, (SELECT 1 UNION ALL SELECT 1));", table, newTable))
+	sqls = append(sqls, fmt.Sprintf("RENAME TABLE `%s` TO `%s`, `%s` TO `%s`;", table, oldTable, newTable, table))
+	return append(sqls, shadowTriggerDropSQL(table)...)
+}
+
+func shadowTriggerDropSQL(table string) []string {
+	var sqls []string
+	for _, event := range []string{"ins", "upd", "upd_del", "del"} {
+		sqls = append(sqls, fmt.Sprintf("DROP TRIGGER IF EXISTS `_%s_osc_%s`;", table, event))
+	}
+	return sqls
+}
+
+// shadowCleanupSQL 影子表复制中途出错时删除触发器和未完成的新表，RENAME 之前出错时原表不受影响；
+// RENAME 成功后 _表名_new 已不存在，删除不影响数据
+func shadowCleanupSQL(table string) []string {
+	return append(shadowTriggerDropSQL(table), fmt.Sprintf("DROP TABLE IF EXISTS `_%s_new`;", table))
+}
+
+// shadowRefuseReason 不能使用影子表的原因：
+// CREATE TABLE LIKE 不复制外键和触发器，RENAME 后其他表的外键和原有的触发器都在 _表名_old 上；
+// 触发器中的 REPLACE 会覆盖唯一索引冲突的行，可能丢失或转换数据的变更、新增唯一索引和主键直接 ALTER 才会报错；
+// 分块复制需要单字段整数主键
+func shadowRefuseReason(sd *TableAlterData, keys []string, referencedBy []string, triggers []string) string {
+	for _, ch := range sd.Changes {
+		if risk, _ := changeRisk(sd, ch); risk == riskLossy {
+			return fmt.Sprintf("`%s` 的变更可能丢失或转换数据", ch.Name)
+		}
+		if (ch.Kind == changeIndexAdd || ch.Kind == changeIndexReplace) && isUniqueIndex(ch.After) {
+			return fmt.Sprintf("新增唯一索引或主键 `%s`", ch.Name)
+		}
+	}
+	switch {
+	case len(sd.SchemaDiff.Dest.ForeignAll) > 0 || len(sd.SchemaDiff.Source.ForeignAll) > 0:
+		return "有外键"
+	case len(referencedBy) > 0:
+		return "被其他表的外键引用（" + strings.Join(referencedBy, ",") + "）"
+	case len(triggers) > 0:
+		sort.Strings(triggers)
+		return "表上有触发器（" + strings.Join(triggers, ",") + "）"
+	case len(keys) != 1:
+		return "没有单字段主键"
+	}
+	def, _ := sd.SchemaDiff.Dest.Fields.Get(keys[0])
+	if !intColumnReg.MatchString(fieldDefinition(keys[0], def.(string))) {
+		return "主键不是整数"
+	}
+	return ""
+}
+
+// setOnlineSQL 大表的变更改为在线执行
+func (sc *SchemaSync) setOnlineSQL(sd *TableAlterData, cfg *Config) {
+	opt := cfg.onlineOption()
+	st := sc.DestDb.GetTableStatus(sd.Table)
+	if st.Rows < opt.MinRows && st.Size < opt.MinSizeMB*1024*1024 {
+		return
+	}
+	size := fmt.Sprintf("约 %d 行，%d MB", st.Rows, st.Size/1024/1024)
+
+	switch algorithm := onlineAlgorithm(sd, supportInstant(sc.DestDb.Version())); algorithm {
+	case onlineInstant, onlineInplace:
+		for i := range sd.SQL {
+			sd.SQL[i] = withAlgorithm(sd.SQL[i], algorithm)
+		}
+		sd.Comment = fmt.Sprintf("大表（%s），在线变更：ALGORITHM=%s", size, algorithm)
+	case onlineShadow:
+		_, triggerTables := sc.DestDb.GetTriggerNames()
+		var triggers []string
+		for name, table := range triggerTables {
+			if table == sd.Table {
+				triggers = append(triggers, name)
+			}
+		}
+		keys := sc.DestDb.GetPrimaryKeys(sd.Table)
+		if reason := shadowRefuseReason(sd, keys, sc.DestDb.GetReferencingTables(sd.Table), triggers); len(reason) > 0 {
+			sd.Comment = fmt.Sprintf("大表（%s），需要复制数据，但%s，无法使用影子表在线变更", size, reason)
+			return
+		}
+		sd.SQL = shadowCopySQL(sd, keys[0], sc.DestDb.GetKeyRange(sd.Table, keys[0]), opt.ChunkSize)
+		sd.online = onlineShadow
+		sd.Comment = fmt.Sprintf("大表（%s），影子表在线变更，原表保留为 _%s_old，确认无误后删除", size, sd.Table)
+	default:
+		sd.Comment = fmt.Sprintf("大表（%s），变更不支持在线执行", size)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_supportInstant(t *testing.T) {
+	require.True(t, supportInstant("8.0.33"))
+	require.True(t, supportInstant("8.4.0-log"))
+	require.False(t, supportInstant("8.0.28"))
+	require.False(t, supportInstant("5.7.44-log"))
+	require.False(t, supportInstant("10.6.16-MariaDB"))
+}
+
+func Test_onlineAlgorithm(t *testing.T) {
+	sc := &SchemaSync{
+		Config: &Config{Drop: true},
+	}
+	// 加字段、加索引
+	sd := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_6.sql"), testLoadFile("testdata/user_1.sql"), &Config{})
+	require.Equal(t, onlineInplace, onlineAlgorithm(sd, true))
+	require.Equal(t, "ALTER TABLE `user`\nADD `a` int,\nALGORITHM=INPLACE, LOCK=NONE;", withAlgorithm("ALTER TABLE `user`\nADD `a` int;", onlineInplace))
+
+	// 修改字段类型
+	sd = sc.getAlterDataBySchema("user", testLoadFile("testdata/user_1.sql"), testLoadFile("testdata/user_2.sql"), &Config{})
+	require.Equal(t, onlineShadow, onlineAlgorithm(sd, true))
+
+	sd = &TableAlterData{Changes: []*schemaChange{
+		{Kind: changeColumnAdd, Name: "a", After: "`a` int"},
+		{Kind: changeColumnChange, Name: "b", Before: "`b` int NOT NULL DEFAULT '0'", After: "`b` int NOT NULL DEFAULT '1' COMMENT 'b'"},
+	}}
+	require.Equal(t, onlineInstant, onlineAlgorithm(sd, true))
+	require.Equal(t, onlineInplace, onlineAlgorithm(sd, false))
+
+	sd.Changes = append(sd.Changes, &schemaChange{Kind: changeForeignAdd, Name: "fk"})
+	require.Equal(t, onlineNone, onlineAlgorithm(sd, true))
+}
+
+func Test_shadowCopySQL(t *testing.T) {
+	sc := &SchemaSync{
+		Config: &Config{Drop: true},
+	}
+	sd := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_1.sql"), testLoadFile("testdata/user_2.sql"), &Config{})
+	sqls := shadowCopySQL(sd, "id", []int64{1, 25000}, 10000)
+	copySQL := "INSERT INTO `_user_new` (`id`,`email`) SELECT `id`,`email` FROM `user` "
+	onDup := " ON DUPLICATE KEY UPDATE `_user_new`.`id` = `_user_new`.`id`;"
+	replaceSQL := "REPLACE INTO `_user_new` (`id`,`email`) VALUES (NEW.`id`, NEW.`email`)"
+	deleteSQL := "DELETE FROM `_user_new` WHERE `id` = OLD.`id`"
+	require.Equal(t, []string{
+		"CREATE TABLE `_user_new` LIKE `user`;",
+		"ALTER TABLE `_user_new`\n" +
+			"CHANGE `id` `id` bigint unsigned NOT NULL AUTO_INCREMENT,\n" +
+			"CHANGE `email` `email` varchar(1000) NOT NULL DEFAULT '',\n" +
+			"drop `register_time`,\n" +
+			"drop `password`,\n" +
+			"drop `status`;",
+		"CREATE TRIGGER `_user_osc_del` AFTER DELETE ON `user` FOR EACH ROW " + deleteSQL + ";",
+		"CREATE TRIGGER `_user_osc_upd_del` AFTER UPDATE ON `user` FOR EACH ROW " + deleteSQL + ";",
+		"CREATE TRIGGER `_user_osc_upd` AFTER UPDATE ON `user` FOR EACH ROW FOLLOWS `_user_osc_upd_del` " + replaceSQL + ";",
+		"CREATE TRIGGER `_user_osc_ins` AFTER INSERT ON `user` FOR EACH ROW " + replaceSQL + ";",
+		copySQL + "WHERE `id` >= 1 AND `id` < 10001 LOCK IN SHARE MODE" + onDup,
+		copySQL + "WHERE `id` >= 10001 AND `id` < 20001 LOCK IN SHARE MODE" + onDup,
+		copySQL + "WHERE `id` >= 20001 LOCK IN SHARE MODE" + onDup,
+		"DO IF((SELECT COUNT(*) FROM `user`) = (SELECT COUNT(*) FROM `_user_new`), 0, (SELECT 1 UNION ALL SELECT 1));",
+		"RENAME TABLE `user` TO `_user_old`, `_user_new` TO `user`;",
+		"DROP TRIGGER IF EXISTS `_user_osc_ins`;",
+		"DROP TRIGGER IF EXISTS `_user_osc_upd`;",
+		"DROP TRIGGER IF EXISTS `_user_osc_upd_del`;",
+		"DROP TRIGGER IF EXISTS `_user_osc_del`;",
+	}, sqls)
+
+	// 空表
+	sqls = shadowCopySQL(sd, "id", nil, 10000)
+	require.Equal(t, copySQL+"LOCK IN SHARE MODE"+onDup, sqls[6])
+}
+
+func Test_shadowRefuseReason(t *testing.T) {
+	sc := &SchemaSync{
+		Config: &Config{Drop: true},
+	}
+	alter := func(table string, src string, dest string) *TableAlterData {
+		return sc.getAlterDataBySchema(table, src, dest, &Config{})
+	}
+	userDest := "CREATE TABLE `user` (\n`id` int NOT NULL,\n`email` varchar(100) NOT NULL,\n`name` varchar(50),\nPRIMARY KEY (`id`)\n) ENGINE=InnoDB"
+	// 加宽字段，直接 ALTER 也不会失败
+	sd := alter("user", "CREATE TABLE `user` (\n`id` bigint NOT NULL,\n`email` varchar(200) NOT NULL,\n`name` varchar(50),\n"+
+		"PRIMARY KEY (`id`)\n) ENGINE=InnoDB", userDest)
+	require.Empty(t, shadowRefuseReason(sd, []string{"id"}, nil, nil))
+	// RENAME 后其他表的外键指向 _user_old
+	require.Equal(t, "被其他表的外键引用（order,log.user_log）", shadowRefuseReason(sd, []string{"id"}, []string{"order", "log.user_log"}, nil))
+	// CREATE TABLE LIKE 不复制触发器
+	require.Equal(t, "表上有触发器（trg_a,trg_b）", shadowRefuseReason(sd, []string{"id"}, nil, []string{"trg_b", "trg_a"}))
+	require.Equal(t, "没有单字段主键", shadowRefuseReason(sd, nil, nil, nil))
+	require.Equal(t, "主键不是整数", shadowRefuseReason(sd, []string{"email"}, nil, nil))
+
+	// 缩短字段，复制时不能被 IGNORE 截断
+	sd = alter("user", "CREATE TABLE `user` (\n`id` int NOT NULL,\n`email` varchar(100) NOT NULL,\n`name` varchar(20),\n"+
+		"PRIMARY KEY (`id`)\n) ENGINE=InnoDB", userDest)
+	require.Equal(t, "`name` 的变更可能丢失或转换数据", shadowRefuseReason(sd, []string{"id"}, nil, nil))
+
+	// 新增唯一索引，重复的行会被触发器的 REPLACE 覆盖
+	sd = alter("user", "CREATE TABLE `user` (\n`id` int NOT NULL,\n`email` varchar(100) NOT NULL,\n`name` varchar(50),\n`code` int,\n"+
+		"PRIMARY KEY (`id`),\nUNIQUE KEY `uk_code` (`code`)\n) ENGINE=InnoDB", userDest)
+	require.Equal(t, "新增唯一索引或主键 `uk_code`", shadowRefuseReason(sd, []string{"id"}, nil, nil))
+
+	sd = alter("order",
+		"CREATE TABLE `order` (\n`id` bigint NOT NULL,\n`uid` int NOT NULL,\nPRIMARY KEY (`id`),\n"+
+			"CONSTRAINT `fk_uid` FOREIGN KEY (`uid`) REFERENCES `user` (`id`)\n) ENGINE=InnoDB",
+		"CREATE TABLE `order` (\n`id` int NOT NULL,\n`uid` int NOT NULL,\nPRIMARY KEY (`id`),\n"+
+			"CONSTRAINT `fk_uid` FOREIGN KEY (`uid`) REFERENCES `user` (`id`)\n) ENGINE=InnoDB")
+	require.Equal(t, "有外键", shadowRefuseReason(sd, []string{"id"}, nil, nil))
+}
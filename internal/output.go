@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// 输出格式
+const (
+	formatText   = "text"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// 输出记录类型，ndjson 格式下每行一条记录，存储过程、函数、事件的类型为 routineKind
+const (
+	recordTable    = "table"
+	recordDataDiff = "data_diff"
+	recordDataSync = "data_sync"
+	recordView     = "view"
+	recordTrigger  = "trigger"
+	recordExec     = "exec"
+)
+
+// tableRecord 一张表的结构差异
+type tableRecord struct {
+	Kind      string            `json:"kind"`
+	Schema    string            `json:"schema"`
+	Table     string            `json:"table"`
+	AlterType string            `json:"alter_type"`
+	Comment   string            `json:"comment,omitempty"`
+	Changes   []*schemaChange   `json:"changes"`
+	Risk      riskLevel         `json:"risk"`
+	Preflight []*preflightCheck `json:"preflight,omitempty"`
+	SQL       []string          `json:"sql"`
+	Status    string            `json:"status"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// dataDiffRecord 数据对比结果
+type dataDiffRecord struct {
+	Kind    string           `json:"kind"`
+	Schema  string           `json:"schema"`
+	Tables  []string         `json:"tables"`
+	Details []*tableDataDiff `json:"details"`
+}
+
+// routineRecord 存储过程、函数、事件、视图、触发器等对象的差异
+type routineRecord struct {
+	Kind    string   `json:"kind"`
+	Schema  string   `json:"schema"`
+	Name    string   `json:"name"`
+	Comment string   `json:"comment,omitempty"`
+	SQL     []string `json:"sql"`
+	// Blocked 删除的风险超过 -max_risk，-sync 时不执行
+	Blocked bool `json:"blocked,omitempty"`
+
+	// undo 恢复目标库原定义的语句
+	undo []string
+}
+
+type schemaRecord struct {
+	Schema       string            `json:"schema"`
+	SourceSchema string            `json:"source_schema,omitempty"`
+	Tables       []*tableRecord    `json:"tables"`
+	DataDiff     *dataDiffRecord   `json:"data_diff,omitempty"`
+	DataSync     []*dataSyncRecord `json:"data_sync"`
+	Procedures   []*routineRecord  `json:"procedures"`
+	Functions    []*routineRecord  `json:"functions"`
+	Events       []*routineRecord  `json:"events"`
+	Views        []*routineRecord  `json:"views"`
+	Triggers     []*routineRecord  `json:"triggers"`
+}
+
+// execRecord 在目标库执行的所有语句的结果
+type execRecord struct {
+	Kind       string        `json:"kind"`
+	Statements []*stmtResult `json:"statements"`
+}
+
+type runRecord struct {
+	Version  string          `json:"version"`
+	Sync     bool            `json:"sync"`
+	Drop     bool            `json:"drop"`
+	Schemas  []*schemaRecord `json:"schemas"`
+	Executed []*stmtResult   `json:"executed,omitempty"`
+}
+
+// resultOutput 按配置的格式输出对比结果
+// text 格式直接打印 sql，json 格式在所有库处理完后输出一个文档，ndjson 格式每条记录输出一行
+type resultOutput struct {
+	format  string
+	w       io.Writer
+	run     *runRecord
+	current *schemaRecord
+}
+
+var output = &resultOutput{format: formatText, w: os.Stdout}
+
+func (o *resultOutput) isText() bool {
+	return o.format == formatText
+}
+
+// printf 仅在 text 格式下输出
+func (o *resultOutput) printf(format string, args ...any) {
+	if o.isText() {
+		fmt.Fprintf(o.w, format, args...)
+	}
+}
+
+// println 仅在 text 格式下输出
+func (o *resultOutput) println(args ...any) {
+	if o.isText() {
+		fmt.Fprintln(o.w, args...)
+	}
+}
+
+// beginSchema 开始输出一个数据库的结果，schema 为目标数据库名
+func (o *resultOutput) beginSchema(cfg *Config, sourceSchema string, schema string) {
+	if o.run == nil {
+		o.run = &runRecord{
+			Version: Version,
+			Sync:    cfg.Sync,
+			Drop:    cfg.Drop,
+			Schemas: make([]*schemaRecord, 0),
+		}
+	}
+	o.current = &schemaRecord{
+		Schema:     schema,
+		Tables:     make([]*tableRecord, 0),
+		Procedures: make([]*routineRecord, 0),
+		Functions:  make([]*routineRecord, 0),
+		Events:     make([]*routineRecord, 0),
+		Views:      make([]*routineRecord, 0),
+		Triggers:   make([]*routineRecord, 0),
+		DataSync:   make([]*dataSyncRecord, 0),
+	}
+	o.run.Schemas = append(o.run.Schemas, o.current)
+	if sourceSchema != schema {
+		o.current.SourceSchema = sourceSchema
+		o.printf("-- ---------------------- db %s => %s -------------------------\n", sourceSchema, schema)
+		return
+	}
+	o.printf("-- ---------------------- db %s -------------------------\n", schema)
+}
+
+func (o *resultOutput) addTable(rec *tableRecord) {
+	rec.Kind = recordTable
+	rec.Schema = o.current.Schema
+	o.current.Tables = append(o.current.Tables, rec)
+	drift.schemaDrift++
+	o.writeRecord(rec)
+}
+
+func (o *resultOutput) addDataDiff(tables []string, details []*tableDataDiff) {
+	if details == nil {
+		details = make([]*tableDataDiff, 0)
+	}
+	rec := &dataDiffRecord{
+		Kind:    recordDataDiff,
+		Schema:  o.current.Schema,
+		Tables:  tables,
+		Details: details,
+	}
+	o.current.DataDiff = rec
+	drift.dataDrift += len(tables)
+	o.writeRecord(rec)
+}
+
+func (o *resultOutput) addRoutine(kind routineKind, rec *routineRecord) {
+	rec.Kind = string(kind)
+	rec.Schema = o.current.Schema
+	switch kind {
+	case routineProcedure:
+		o.current.Procedures = append(o.current.Procedures, rec)
+	case routineFunction:
+		o.current.Functions = append(o.current.Functions, rec)
+	case routineEvent:
+		o.current.Events = append(o.current.Events, rec)
+	}
+	drift.procedureDrift++
+	o.writeRecord(rec)
+}
+
+func (o *resultOutput) addView(rec *routineRecord) {
+	rec.Kind = recordView
+	rec.Schema = o.current.Schema
+	o.current.Views = append(o.current.Views, rec)
+	drift.schemaDrift++
+	o.writeRecord(rec)
+}
+
+func (o *resultOutput) addTrigger(rec *routineRecord) {
+	rec.Kind = recordTrigger
+	rec.Schema = o.current.Schema
+	o.current.Triggers = append(o.current.Triggers, rec)
+	drift.schemaDrift++
+	o.writeRecord(rec)
+}
+
+func (o *resultOutput) addDataSync(rec *dataSyncRecord) {
+	rec.Kind = recordDataSync
+	rec.Schema = o.current.Schema
+	o.current.DataSync = append(o.current.DataSync, rec)
+	o.writeRecord(rec)
+}
+
+func (o *resultOutput) writeRecord(rec any) {
+	if o.format != formatNDJSON {
+		return
+	}
+	bs, err := json.Marshal(rec)
+	if err != nil {
+		log.Println("marshal record failed:", err)
+		return
+	}
+	o.w.Write(append(bs, '\n'))
+}
+
+// FlushOutput json 格式下输出完整的对比结果
+func FlushOutput() {
+	if output.format != formatJSON || output.run == nil {
+		return
+	}
+	bs, err := json.MarshalIndent(output.run, "", "  ")
+	if err != nil {
+		log.Println("marshal result failed:", err)
+		return
+	}
+	fmt.Fprintln(output.w, string(bs))
+}
+
+func newTableRecord(sd *TableAlterData) *tableRecord {
+	changes := sd.Changes
+	if changes == nil {
+		changes = make([]*schemaChange, 0)
+	}
+	return &tableRecord{
+		Table:     sd.Table,
+		AlterType: sd.Type.String(),
+		Comment:   sd.Comment,
+		Changes:   changes,
+		SQL:       sd.SQL,
+		Status:    "preview",
+	}
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testOutput 将输出写入 buf，测试结束后恢复
+func testOutput(t *testing.T, format string) *strings.Builder {
+	buf := &strings.Builder{}
+	savedOutput, savedDrift := output, drift
+	output = &resultOutput{format: format, w: buf}
+	drift = &driftSummary{}
+	t.Cleanup(func() {
+		output, drift = savedOutput, savedDrift
+	})
+	return buf
+}
+
+// testWriteRun 按同步流程输出两个库的结果，text 格式的内容不能出现在 json 中
+func testWriteRun() {
+	cfg := &Config{Sync: true}
+	output.beginSchema(cfg, "a", "a")
+	output.printf("-- Table : user\nALTER TABLE `user` ADD `a` int;\n")
+	output.println("# no data of tables in difference")
+	output.addTable(&tableRecord{
+		Table:     "user",
+		AlterType: alterTypeAlter.String(),
+		Changes:   []*schemaChange{{Kind: changeColumnAdd, Name: "a", After: "`a` int", Risk: riskRebuild}},
+		Risk:      riskRebuild,
+		SQL:       []string{"ALTER TABLE `user` ADD `a` int;"},
+		Status:    stmtSuccess,
+	})
+	output.addDataDiff([]string{"sys_cfg"}, []*tableDataDiff{{Table: "sys_cfg", Keys: []string{"id"}, Inserted: [][]string{{"1"}}}})
+	output.addRoutine(routineProcedure, &routineRecord{Name: "p1", SQL: []string{"DROP PROCEDURE IF EXISTS `p1`"}})
+	output.addView(&routineRecord{Name: "v1", SQL: []string{"CREATE OR REPLACE VIEW `v1` AS select 1"}})
+	output.addTrigger(&routineRecord{Name: "tr1", Blocked: true, SQL: []string{"DROP TRIGGER IF EXISTS `tr1`"}})
+	output.addDataSync(&dataSyncRecord{Table: "sys_cfg", Inserts: 1, Status: stmtSuccess})
+	output.beginSchema(cfg, "a", "b")
+	output.printf("-- Table : order\n")
+}
+
+func Test_resultOutput_ndjson(t *testing.T) {
+	buf := testOutput(t, formatNDJSON)
+	testWriteRun()
+	FlushOutput()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	var kinds []string
+	records := make(map[string]map[string]any)
+	for _, line := range lines {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec), line)
+		kinds = append(kinds, rec["kind"].(string))
+		records[rec["kind"].(string)] = rec
+		require.Equal(t, "a", rec["schema"])
+	}
+	require.Equal(t, []string{recordTable, recordDataDiff, "procedure", recordView, recordTrigger, recordDataSync}, kinds)
+
+	table := records[recordTable]
+	require.Equal(t, "user", table["table"])
+	require.Equal(t, "alter", table["alter_type"])
+	require.Equal(t, "rebuild", table["risk"])
+	require.Equal(t, stmtSuccess, table["status"])
+	require.Equal(t, []any{"ALTER TABLE `user` ADD `a` int;"}, table["sql"])
+	require.Equal(t, map[string]any{"kind": "column_add", "name": "a", "after": "`a` int", "risk": "rebuild"}, table["changes"].([]any)[0])
+	require.Equal(t, []any{"sys_cfg"}, records[recordDataDiff]["tables"])
+	require.Equal(t, map[string]any{"table": "sys_cfg", "keys": []any{"id"}, "inserted": []any{[]any{"1"}}},
+		records[recordDataDiff]["details"].([]any)[0])
+	require.Equal(t, "p1", records["procedure"]["name"])
+	require.Equal(t, true, records[recordTrigger]["blocked"])
+	require.NotContains(t, records[recordView], "blocked")
+	require.Equal(t, 1.0, records[recordDataSync]["inserts"])
+}
+
+func Test_resultOutput_json(t *testing.T) {
+	buf := testOutput(t, formatJSON)
+	testWriteRun()
+	FlushOutput()
+
+	// 整个输出是一个 json 文档
+	dec := json.NewDecoder(strings.NewReader(buf.String()))
+	var run map[string]any
+	require.NoError(t, dec.Decode(&run))
+	require.False(t, dec.More())
+
+	require.Equal(t, Version, run["version"])
+	require.Equal(t, true, run["sync"])
+	schemas := run["schemas"].([]any)
+	require.Len(t, schemas, 2)
+	first, second := schemas[0].(map[string]any), schemas[1].(map[string]any)
+	require.Equal(t, "a", first["schema"])
+	require.NotContains(t, first, "source_schema")
+	require.Equal(t, "b", second["schema"])
+	require.Equal(t, "a", second["source_schema"])
+	require.Empty(t, second["tables"])
+
+	table := first["tables"].([]any)[0].(map[string]any)
+	require.Equal(t, recordTable, table["kind"])
+	require.Equal(t, "user", table["table"])
+	require.Equal(t, "rebuild", table["risk"])
+	require.Equal(t, []any{"sys_cfg"}, first["data_diff"].(map[string]any)["tables"])
+	require.Equal(t, "p1", first["procedures"].([]any)[0].(map[string]any)["name"])
+	require.Empty(t, first["functions"])
+	require.Equal(t, "v1", first["views"].([]any)[0].(map[string]any)["name"])
+	require.Equal(t, true, first["triggers"].([]any)[0].(map[string]any)["blocked"])
+	require.Equal(t, stmtSuccess, first["data_sync"].([]any)[0].(map[string]any)["status"])
+}
@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// preflightCheck 变更前在目标库统计会被截断、转换或导致变更失败的行数
+type preflightCheck struct {
+	Name  string `json:"name"`
+	Check string `json:"check"`
+	SQL   string `json:"sql"`
+	Rows  int64  `json:"rows"`
+	Error string `json:"error,omitempty"`
+
+	change *schemaChange
+}
+
+// intTypeRange 整数类型的取值范围
+func intTypeRange(ct *columnType) (string, string) {
+	bits := intTypeBytes[ct.name] * 8
+	if ct.unsigned {
+		return "0", fmt.Sprint(uint64(math.MaxUint64) >> (64 - bits))
+	}
+	return fmt.Sprint(int64(-1) << (bits - 1)), fmt.Sprint(int64(math.MaxInt64) >> (64 - bits))
+}
+
+func isNumericType(ct *columnType) bool {
+	_, isInt := intTypeBytes[ct.name]
+	return isInt || decimalTypes[ct.name] || floatTypes[ct.name] > 0
+}
+
+// columnConditions 修改字段定义后已有数据会出问题的条件，字段名使用目标库中的原名
+func columnConditions(col string, bt *columnType, at *columnType) []string {
+	var conds []string
+	if !bt.notNull && at.notNull {
+		conds = append(conds, fmt.Sprintf("`%s` IS NULL", col))
+	}
+	if n := at.stringLength(); n >= 0 && (bt.stringLength() < 0 || n < bt.stringLength()) {
+		fn := "CHAR_LENGTH"
+		if _, isLob := lobTypeLength[at.name]; isLob || at.isBinary() {
+			fn = "LENGTH"
+		}
+		conds = append(conds, fmt.Sprintf("%s(`%s`) > %d", fn, col, n))
+	}
+	if _, isInt := intTypeBytes[at.name]; isInt && isNumericType(bt) && typeRisk(bt, at) == riskLossy {
+		lo, hi := intTypeRange(at)
+		conds = append(conds, fmt.Sprintf("(`%s` < %s OR `%s` > %s)", col, lo, col, hi))
+	}
+	if decimalTypes[at.name] && isNumericType(bt) {
+		digits, scale := at.arg(0)-at.arg(1), at.arg(1)
+		if !decimalTypes[bt.name] || digits < bt.arg(0)-bt.arg(1) {
+			conds = append(conds, fmt.Sprintf("ABS(`%s`) >= 1e%d", col, digits))
+		}
+		if !decimalTypes[bt.name] || scale < bt.arg(1) {
+			conds = append(conds, fmt.Sprintf("`%s` <> ROUND(`%s`, %d)", col, col, scale))
+		}
+	}
+	if enumTypes[at.name] && bt.name == at.name {
+		var removed []string
+		for _, v := range bt.args {
+			if !inStringSlice(v, at.args) {
+				removed = append(removed, v)
+			}
+		}
+		if len(removed) > 0 && at.name == "enum" {
+			conds = append(conds, fmt.Sprintf("`%s` IN (%s)", col, strings.Join(removed, ",")))
+		}
+		for _, v := range removed {
+			if at.name == "set" {
+				conds = append(conds, fmt.Sprintf("FIND_IN_SET(%s, `%s`) > 0", v, col))
+			}
+		}
+	}
+	switch {
+	case bt.name == at.name && (at.name == "datetime" || at.name == "timestamp" || at.name == "time") && at.arg(0) < bt.arg(0):
+		conds = append(conds, fmt.Sprintf("MICROSECOND(`%s`) %% %d <> 0", col, int64(math.Pow10(int(6-at.arg(0))))))
+	case (bt.name == "datetime" || bt.name == "timestamp") && at.name == "date":
+		conds = append(conds, fmt.Sprintf("TIME(`%s`) <> '00:00:00'", col))
+	case (bt.name == "datetime" || bt.name == "date") && at.name == "timestamp":
+		conds = append(conds, fmt.Sprintf("`%s` NOT BETWEEN '1970-01-01 00:00:01' AND '2038-01-19 03:14:07'", col))
+	}
+	if len(bt.charset) > 0 && len(at.charset) > 0 && bt.charset != at.charset && !inStringSlice(bt.charset, lossless4Charset[at.charset]) {
+		conds = append(conds, fmt.Sprintf("BINARY `%s` <> BINARY CONVERT(CONVERT(`%s` USING %s) USING %s)", col, col, at.charset, bt.charset))
+	}
+	return conds
+}
+
+// indexColumnExprs 索引中的字段，前缀索引使用 LEFT(字段, 长度)，函数索引返回 nil
+func indexColumnExprs(def string) []string {
+	start := strings.Index(def, "(")
+	end := strings.LastIndex(def, ")")
+	if start < 0 || end < start || strings.HasPrefix(def[start:], "((") {
+		return nil
+	}
+	var exprs []string
+	for _, part := range strings.Split(def[start+1:end], ",") {
+		fields := strings.Split(strings.TrimSpace(part), "`")
+		if len(fields) < 3 {
+			return nil
+		}
+		expr := "`" + fields[1] + "`"
+		if rest := strings.TrimSpace(fields[2]); strings.HasPrefix(rest, "(") {
+			expr = fmt.Sprintf("LEFT(%s, %s)", expr, strings.Trim(strings.Fields(rest)[0], "()"))
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs
+}
+
+// preflightChecks 需要在目标库检查已有数据的变更：修改字段定义、新增唯一索引和主键
+func preflightChecks(sd *TableAlterData) []*preflightCheck {
+	// 重命名的字段，新字段名 => 目标库中的原名
+	oldNames := make(map[string]string)
+	for _, ch := range sd.Changes {
+		if ch.Kind == changeColumnRename {
+			oldNames[ch.Name] = ch.From
+		}
+	}
+	var checks []*preflightCheck
+	count := func(ch *schemaChange, check string, query string) {
+		checks = append(checks, &preflightCheck{Name: ch.Name, Check: check, SQL: query, change: ch})
+	}
+	for _, ch := range sd.Changes {
+		switch ch.Kind {
+		case changeColumnChange, changeColumnRename:
+			col := ch.Name
+			if len(ch.From) > 0 {
+				col = ch.From
+			}
+			for _, cond := range columnConditions(col, parseColumnType(ch.Before), parseColumnType(ch.After)) {
+				count(ch, "where "+cond, fmt.Sprintf("SELECT COUNT(*) FROM `%s` WHERE %s", sd.Table, cond))
+			}
+		case changeIndexAdd, changeIndexReplace:
+			if !strings.HasPrefix(ch.After, "UNIQUE") && !strings.HasPrefix(ch.After, "PRIMARY") {
+				continue
+			}
+			exprs := indexColumnExprs(ch.After)
+			if len(exprs) == 0 {
+				continue
+			}
+			var notNull []string
+			for i, expr := range exprs {
+				for newName, oldName := range oldNames {
+					expr = strings.ReplaceAll(expr, "`"+newName+"`", "`"+oldName+"`")
+				}
+				name := strings.Split(expr, "`")[1]
+				if _, has := sd.SchemaDiff.Dest.Fields.Get(name); !has {
+					// 索引包含新增的字段
+					exprs = nil
+					break
+				}
+				exprs[i] = expr
+				notNull = append(notNull, fmt.Sprintf("`%s` IS NOT NULL", name))
+			}
+			if len(exprs) == 0 {
+				continue
+			}
+			where := ""
+			if strings.HasPrefix(ch.After, "UNIQUE") {
+				// 唯一索引允许多个 NULL
+				where = " WHERE " + strings.Join(notNull, " AND ")
+			}
+			keys := strings.Join(exprs, ", ")
+			count(ch, "duplicated on ("+keys+")", fmt.Sprintf("SELECT COALESCE(SUM(cnt), 0) FROM (SELECT COUNT(*) AS cnt FROM `%s`%s GROUP BY %s HAVING cnt > 1) d", sd.Table, where, keys))
+		}
+	}
+	return checks
+}
+
+// preflightTimeout 单条检查语句的超时时间，默认 30 秒
+func (cfg *Config) preflightTimeout() time.Duration {
+	if cfg.PreflightTimeout > 0 {
+		return time.Duration(cfg.PreflightTimeout) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// preflightQuery 检查语句加上 MAX_EXECUTION_TIME，超时后服务端也停止执行（MySQL 5.7.8+）
+func preflightQuery(query string, timeout time.Duration) string {
+	return strings.Replace(query, "SELECT ", fmt.Sprintf("SELECT /*+ MAX_EXECUTION_TIME(%d) */ ", timeout.Milliseconds()), 1)
+}
+
+// preflight 使用 -preflight 时在目标库执行检查，有问题数据或检查失败的变更风险提升为 lossy
+func (sc *SchemaSync) preflight(sd *TableAlterData, cfg *Config) []*preflightCheck {
+	if !cfg.Preflight || sd.Type != alterTypeAlter || sc.DestDb.snapshot != nil {
+		return nil
+	}
+	checks := preflightChecks(sd)
+	timeout := cfg.preflightTimeout()
+	for _, pc := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := sc.DestDb.Db.QueryRowContext(ctx, preflightQuery(pc.SQL, timeout)).Scan(&pc.Rows)
+		cancel()
+		reason := fmt.Sprintf("%d rows %s", pc.Rows, pc.Check)
+		if err != nil {
+			pc.Error = err.Error()
+			log.Println("preflight check failed:", pc.SQL, err)
+			reason = "check failed: rows " + pc.Check
+		} else if pc.Rows == 0 {
+			continue
+		}
+		ch := pc.change
+		ch.Risk = max(ch.Risk, riskLossy)
+		if len(ch.RiskReason) > 0 {
+			reason = ch.RiskReason + ", " + reason
+		}
+		ch.RiskReason = reason
+	}
+	return checks
+}
+
+// preflightComment 预览中检查结果的注释
+func preflightComment(checks []*preflightCheck) string {
+	var buf strings.Builder
+	for _, pc := range checks {
+		if len(pc.Error) > 0 {
+			fmt.Fprintf(&buf, "-- preflight `%s`: rows %s, check failed: %s\n", pc.Name, pc.Check, pc.Error)
+		} else {
+			fmt.Fprintf(&buf, "-- preflight `%s`: %d rows %s\n", pc.Name, pc.Rows, pc.Check)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_columnConditions(t *testing.T) {
+	conds := func(before string, after string) []string {
+		return columnConditions("a", parseColumnType(before), parseColumnType(after))
+	}
+	require.Equal(t, []string{"`a` IS NULL", "CHAR_LENGTH(`a`) > 50"}, conds("`a` varchar(255) DEFAULT NULL", "`a` varchar(50) NOT NULL"))
+	require.Equal(t, []string{"LENGTH(`a`) > 255"}, conds("`a` text", "`a` tinytext"))
+	require.Equal(t, []string{"(`a` < -2147483648 OR `a` > 2147483647)"}, conds("`a` bigint", "`a` int"))
+	require.Equal(t, []string{"(`a` < 0 OR `a` > 255)"}, conds("`a` int", "`a` tinyint unsigned"))
+	require.Equal(t, []string{"ABS(`a`) >= 1e6", "`a` <> ROUND(`a`, 1)"}, conds("`a` decimal(10,2)", "`a` decimal(7,1)"))
+	require.Equal(t, []string{"`a` IN ('c')"}, conds("`a` enum('a','b','c')", "`a` enum('a','b')"))
+	require.Equal(t, []string{"FIND_IN_SET('c', `a`) > 0"}, conds("`a` set('a','b','c')", "`a` set('a','b')"))
+	require.Equal(t, []string{"MICROSECOND(`a`) % 1000 <> 0"}, conds("`a` datetime(6)", "`a` datetime(3)"))
+	require.Equal(t, []string{"TIME(`a`) <> '00:00:00'"}, conds("`a` datetime", "`a` date"))
+	require.Equal(t, []string{"`a` NOT BETWEEN '1970-01-01 00:00:01' AND '2038-01-19 03:14:07'"}, conds("`a` date", "`a` timestamp"))
+	require.Equal(t, []string{"BINARY `a` <> BINARY CONVERT(CONVERT(`a` USING latin1) USING utf8mb4)"},
+		conds("`a` varchar(10) CHARACTER SET utf8mb4", "`a` varchar(10) CHARACTER SET latin1"))
+	require.Empty(t, conds("`a` varchar(50)", "`a` varchar(255)"))
+	require.Empty(t, conds("`a` int", "`a` bigint"))
+}
+
+func Test_indexColumnExprs(t *testing.T) {
+	require.Equal(t, []string{"`a`", "LEFT(`b`, 10)"}, indexColumnExprs("UNIQUE KEY `uk` (`a`,`b`(10) DESC)"))
+	require.Equal(t, []string{"`id`"}, indexColumnExprs("PRIMARY KEY (`id`)"))
+	require.Nil(t, indexColumnExprs("UNIQUE KEY `uk` ((lower(`a`)))"))
+}
+
+func Test_preflightChecks(t *testing.T) {
+	sc := &SchemaSync{Config: &Config{
+		Renames: map[string]map[string]string{"user": {"email": "mail"}},
+	}}
+	sd := sc.getAlterDataBySchema("user",
+		"CREATE TABLE `user` (\n`id` int NOT NULL,\n`mail` varchar(100) NOT NULL,\n`name` varchar(20),\n`age` int,\n"+
+			"PRIMARY KEY (`id`),\nUNIQUE KEY `uk_mail` (`mail`),\nUNIQUE KEY `uk_age` (`age`),\nKEY `idx_name` (`name`)\n) ENGINE=InnoDB",
+		"CREATE TABLE `user` (\n`id` int NOT NULL,\n`email` varchar(100) NOT NULL,\n`name` varchar(50),\n"+
+			"PRIMARY KEY (`id`)\n) ENGINE=InnoDB",
+		&Config{})
+
+	checks := make(map[string]string)
+	for _, pc := range preflightChecks(sd) {
+		checks[pc.Name] = pc.SQL
+	}
+	require.Equal(t, map[string]string{
+		"name":    "SELECT COUNT(*) FROM `user` WHERE CHAR_LENGTH(`name`) > 20",
+		"uk_mail": "SELECT COALESCE(SUM(cnt), 0) FROM (SELECT COUNT(*) AS cnt FROM `user` WHERE `email` IS NOT NULL GROUP BY `email` HAVING cnt > 1) d",
+	}, checks)
+
+	require.Equal(t, "-- preflight `name`: 3 rows where CHAR_LENGTH(`name`) > 20\n",
+		preflightComment([]*preflightCheck{{Name: "name", Check: "where CHAR_LENGTH(`name`) > 20", Rows: 3}}))
+}
+
+func Test_preflightQuery(t *testing.T) {
+	require.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(30000) */ COUNT(*) FROM `user` WHERE `a` IS NULL",
+		preflightQuery("SELECT COUNT(*) FROM `user` WHERE `a` IS NULL", (&Config{}).preflightTimeout()))
+	require.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(5000) */ COALESCE(SUM(cnt), 0) FROM (SELECT COUNT(*) AS cnt FROM `user`) d",
+		preflightQuery("SELECT COALESCE(SUM(cnt), 0) FROM (SELECT COUNT(*) AS cnt FROM `user`) d", (&Config{PreflightTimeout: 5}).preflightTimeout()))
+}
+
+func Test_SchemaSync_preflight(t *testing.T) {
+	_, db := newFakeDB("fake_preflight")
+	sc := &SchemaSync{Config: &Config{}, DestDb: db}
+	sd := sc.getAlterDataBySchema("user",
+		"CREATE TABLE `user` (\n`id` int NOT NULL,\n`name` varchar(20),\nPRIMARY KEY (`id`)\n) ENGINE=InnoDB",
+		"CREATE TABLE `user` (\n`id` int NOT NULL,\n`name` varchar(50),\nPRIMARY KEY (`id`)\n) ENGINE=InnoDB",
+		&Config{})
+	classifyRisk(sd)
+
+	// 默认不执行
+	require.Nil(t, sc.preflight(sd, &Config{}))
+	require.NotContains(t, sd.Changes[0].RiskReason, "check failed")
+
+	// 检查失败时按 lossy 处理，并注明原因
+	checks := sc.preflight(sd, &Config{Preflight: true})
+	require.Len(t, checks, 1)
+	require.NotEmpty(t, checks[0].Error)
+	require.Equal(t, riskLossy, sd.risk())
+	require.Contains(t, sd.Changes[0].RiskReason, "check failed: rows where CHAR_LENGTH(`name`) > 20")
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var createTableNameReg = regexp.MustCompile("^CREATE TABLE `[^`]+`")
+
+// renameTableSchema 替换建表语句中的表名
+func renameTableSchema(schema string, from string, to string) string {
+	return strings.Replace(schema, fmt.Sprintf("CREATE TABLE `%s`", from), fmt.Sprintf("CREATE TABLE `%s`", to), 1)
+}
+
+// normalizeTableSchema 去除表名和 AUTO_INCREMENT=xxx，用于比较不同名的表结构是否相同
+func normalizeTableSchema(schema string) string {
+	schema = createTableNameReg.ReplaceAllString(strings.TrimSpace(schema), "CREATE TABLE")
+	return autoIncrOptionReg.ReplaceAllString(schema, "")
+}
+
+// detectTableRenames 识别重命名的表：源数据库新建的表和目标数据库多余的表结构完全相同，且一一对应。
+// drop 时生成 RENAME TABLE 代替新建和删除，否则只在新建的表上提示
+func detectTableRenames(sds []*TableAlterData, cfg *Config) {
+	var creates, drops []*TableAlterData
+	for _, sd := range sds {
+		switch sd.Type {
+		case alterTypeCreate:
+			creates = append(creates, sd)
+		case alterTypeDropTable:
+			drops = append(drops, sd)
+		}
+	}
+
+	matchOf := func(sd *TableAlterData, candidates []*TableAlterData, schemaOf func(*TableAlterData) string) []*TableAlterData {
+		var matches []*TableAlterData
+		for _, c := range candidates {
+			if normalizeTableSchema(schemaOf(sd)) == normalizeTableSchema(schemaOf(c)) {
+				matches = append(matches, c)
+			}
+		}
+		return matches
+	}
+	srcSchema := func(sd *TableAlterData) string {
+		if sd.Type == alterTypeCreate {
+			return sd.SchemaDiff.SourceRaw
+		}
+		return sd.SchemaDiff.DestRaw
+	}
+
+	for _, create := range creates {
+		matches := matchOf(create, drops, srcSchema)
+		if len(matches) != 1 || len(matchOf(matches[0], creates, srcSchema)) != 1 {
+			continue
+		}
+		drop := matches[0]
+		create.RenameFrom = drop.Table
+		if !cfg.Drop {
+			continue
+		}
+		create.Type = alterTypeRename
+		create.Comment = "由目标数据库的表 " + drop.Table + " 重命名而来"
+		create.Changes = nil
+		create.addChange(changeTableRename, create.Table, drop.Table, create.Table)
+		create.SQL = alterTableSQL(create.Table, create.Changes, false)
+		drop.Type = alterTypeNo
+	}
+}
+
+// fieldDefinition 去除字段名后的字段定义，用于比较两个不同名字段的定义是否相同
+func fieldDefinition(name string, line string) string {
+	for _, quote := range []string{"`", `"`} {
+		if prefix := quote + name + quote; strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return line
+}
+
+// getColumnRenames 识别重命名的字段，返回 旧字段名 => 新字段名。
+// 优先使用配置 renames 中声明的重命名，其次识别 位置相同、定义相同、只有名字不同 的字段
+func (sc *SchemaSync) getColumnRenames(alter *TableAlterData) map[string]string {
+	sourceMyS := alter.SchemaDiff.Source
+	destMyS := alter.SchemaDiff.Dest
+	renames := make(map[string]string)
+	renamed := make(map[string]bool)
+
+	for oldName, newName := range sc.Config.Renames[alter.Table] {
+		_, oldInSrc := sourceMyS.Fields.Get(oldName)
+		_, oldInDst := destMyS.Fields.Get(oldName)
+		_, newInSrc := sourceMyS.Fields.Get(newName)
+		_, newInDst := destMyS.Fields.Get(newName)
+		if oldInSrc || !oldInDst || !newInSrc || newInDst {
+			log.Println("ignore rename", alter.Table, oldName, "=>", newName, ": column not match")
+			continue
+		}
+		renames[oldName] = newName
+		renamed[newName] = true
+	}
+
+	srcNames := sourceMyS.GetFieldNames()
+	dstNames := destMyS.GetFieldNames()
+	for i := 0; i < len(srcNames) && i < len(dstNames); i++ {
+		newName, oldName := srcNames[i], dstNames[i]
+		if _, has := destMyS.Fields.Get(newName); has || renamed[newName] {
+			continue
+		}
+		if _, has := sourceMyS.Fields.Get(oldName); has || len(renames[oldName]) > 0 {
+			continue
+		}
+		newDt, _ := sourceMyS.Fields.Get(newName)
+		oldDt, _ := destMyS.Fields.Get(oldName)
+		if fieldDefinition(newName, newDt.(string)) == fieldDefinition(oldName, oldDt.(string)) {
+			renames[oldName] = newName
+			renamed[newName] = true
+		}
+	}
+	return renames
+}
+
+// renameIndexColumns 将索引定义中重命名的字段替换为新字段名，重命名字段时 mysql 会同步修改索引。
+// 只替换第一个括号内的字段列表，索引名和外键引用的字段不变
+func renameIndexColumns(sql string, renames map[string]string) string {
+	start := strings.Index(sql, "(")
+	if len(renames) == 0 || start < 0 {
+		return sql
+	}
+	end := strings.Index(sql[start:], ")")
+	if end < 0 {
+		return sql
+	}
+	end += start
+	columns := sql[start:end]
+	for oldName, newName := range renames {
+		columns = strings.ReplaceAll(columns, "`"+oldName+"`", "`"+newName+"`")
+	}
+	return sql[:start] + columns + sql[end:]
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_detectTableRenames(t *testing.T) {
+	sc := &SchemaSync{Config: &Config{}}
+	newSds := func(cfg *Config) []*TableAlterData {
+		return []*TableAlterData{
+			sc.getAlterDataBySchema("member", renameTableSchema(testLoadFile("testdata/user_0.sql"), "user", "member"), "", cfg),
+			sc.getAlterDataBySchema("user", "", testLoadFile("testdata/user_0.sql"), cfg),
+			sc.getAlterDataBySchema("user_bak", "", renameTableSchema(testLoadFile("testdata/user_1.sql"), "user", "user_bak"), cfg),
+		}
+	}
+
+	cfg := &Config{}
+	sds := newSds(cfg)
+	detectTableRenames(sds, cfg)
+	require.Equal(t, alterTypeCreate, sds[0].Type)
+	require.Equal(t, "user", sds[0].RenameFrom)
+	require.Equal(t, alterTypeDropTable, sds[1].Type)
+
+	cfg = &Config{Drop: true}
+	sds = newSds(cfg)
+	detectTableRenames(sds, cfg)
+	require.Equal(t, alterTypeRename, sds[0].Type)
+	require.Equal(t, []string{"RENAME TABLE `user` TO `member`;"}, sds[0].SQL)
+	require.Equal(t, alterTypeNo, sds[1].Type)
+	require.Equal(t, alterTypeDropTable, sds[2].Type)
+}
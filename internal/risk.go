@@ -0,0 +1,397 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// riskLevel 变更的风险等级
+type riskLevel int
+
+const (
+	// riskSafe 只修改元数据或不影响已有数据
+	riskSafe riskLevel = iota
+	// riskRebuild 需要重建表，大表耗时长
+	riskRebuild
+	// riskLossy 可能截断、转换已有数据或因已有数据执行失败
+	riskLossy
+	// riskDestructive 删除表或字段
+	riskDestructive
+)
+
+var riskNames = []string{"safe", "rebuild", "lossy", "destructive"}
+
+func (r riskLevel) String() string {
+	return riskNames[r]
+}
+
+func (r riskLevel) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func parseRiskLevel(name string) (riskLevel, bool) {
+	for i, n := range riskNames {
+		if n == name {
+			return riskLevel(i), true
+		}
+	}
+	return riskSafe, false
+}
+
+// columnType 字段定义中的类型部分
+type columnType struct {
+	name     string
+	args     []string
+	unsigned bool
+	charset  string
+	notNull  bool
+}
+
+var (
+	columnTypeReg    = regexp.MustCompile("^(`[^`]+`|\"[^\"]+\")\\s+(\\w+)(\\(((?:'(?:[^'\\\\]|\\\\.|'')*'|[^)'])*)\\))?(\\s+unsigned)?")
+	columnCharsetReg = regexp.MustCompile(`\sCHARACTER SET (\w+)`)
+)
+
+func parseColumnType(def string) *columnType {
+	m := columnTypeReg.FindStringSubmatch(def)
+	if m == nil {
+		return &columnType{}
+	}
+	ct := &columnType{
+		name:     strings.ToLower(m[2]),
+		args:     splitTypeArgs(m[4]),
+		unsigned: len(m[5]) > 0,
+		notNull:  strings.Contains(def, " NOT NULL"),
+	}
+	if cm := columnCharsetReg.FindStringSubmatch(def); cm != nil {
+		ct.charset = cm[1]
+	}
+	return ct
+}
+
+// splitTypeArgs 拆分类型参数，如 decimal(10,2)、enum('a','b')
+func splitTypeArgs(str string) []string {
+	var args []string
+	var buf strings.Builder
+	quoted := false
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(str):
+			buf.WriteByte(c)
+			i++
+			c = str[i]
+		case c == '\'':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			args = append(args, strings.TrimSpace(buf.String()))
+			buf.Reset()
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	if len(str) > 0 {
+		args = append(args, strings.TrimSpace(buf.String()))
+	}
+	return args
+}
+
+func (ct *columnType) String() string {
+	str := ct.name
+	if len(ct.args) > 0 {
+		str += "(" + strings.Join(ct.args, ",") + ")"
+	}
+	if ct.unsigned {
+		str += " unsigned"
+	}
+	return str
+}
+
+func (ct *columnType) arg(i int) int64 {
+	if i >= len(ct.args) {
+		return 0
+	}
+	n, _ := strconv.ParseInt(ct.args[i], 10, 64)
+	return n
+}
+
+var (
+	intTypeBytes = map[string]int{"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "integer": 4, "bigint": 8}
+	// 字符串类型能存储的最大长度，text、blob 按字节
+	lobTypeLength = map[string]int64{
+		"tinytext": 255, "text": 65535, "mediumtext": 16777215, "longtext": 4294967295,
+		"tinyblob": 255, "blob": 65535, "mediumblob": 16777215, "longblob": 4294967295,
+	}
+	decimalTypes = map[string]bool{"decimal": true, "numeric": true, "dec": true, "fixed": true}
+	floatTypes   = map[string]int{"float": 4, "double": 8, "real": 8}
+	enumTypes    = map[string]bool{"enum": true, "set": true}
+	// 日期时间类型的取值范围从小到大
+	timeTypeRank = map[string]int{"year": 0, "date": 1, "timestamp": 2, "datetime": 3}
+)
+
+// stringLength 字符串类型的长度，非字符串类型返回 -1
+func (ct *columnType) stringLength() int64 {
+	switch ct.name {
+	case "char", "varchar", "binary", "varbinary":
+		if len(ct.args) == 0 {
+			return 1
+		}
+		return ct.arg(0)
+	}
+	if n, has := lobTypeLength[ct.name]; has {
+		return n
+	}
+	return -1
+}
+
+func (ct *columnType) isBinary() bool {
+	return strings.Contains(ct.name, "binary") || strings.Contains(ct.name, "blob")
+}
+
+// typeRisk 修改字段类型的风险
+func typeRisk(before *columnType, after *columnType) riskLevel {
+	if before.String() == after.String() {
+		return riskSafe
+	}
+	bInt, bIsInt := intTypeBytes[before.name]
+	aInt, aIsInt := intTypeBytes[after.name]
+	switch {
+	case bIsInt && aIsInt:
+		if aInt < bInt || before.unsigned != after.unsigned {
+			return riskLossy
+		}
+		if aInt == bInt {
+			// 只修改了显示宽度
+			return riskSafe
+		}
+		return riskRebuild
+	case before.stringLength() >= 0 && after.stringLength() >= 0:
+		if after.stringLength() < before.stringLength() || before.isBinary() != after.isBinary() {
+			return riskLossy
+		}
+		return riskRebuild
+	case decimalTypes[before.name] && decimalTypes[after.name]:
+		if after.arg(0)-after.arg(1) < before.arg(0)-before.arg(1) || after.arg(1) < before.arg(1) {
+			return riskLossy
+		}
+		return riskRebuild
+	case floatTypes[before.name] > 0 && floatTypes[after.name] > 0:
+		if floatTypes[after.name] < floatTypes[before.name] {
+			return riskLossy
+		}
+		return riskRebuild
+	case enumTypes[before.name] && before.name == after.name:
+		if len(after.args) >= len(before.args) && strings.Join(after.args[:len(before.args)], ",") == strings.Join(before.args, ",") {
+			// 在末尾增加选项只修改元数据
+			return riskSafe
+		}
+		for _, v := range before.args {
+			if !inStringSlice(v, after.args) {
+				return riskLossy
+			}
+		}
+		return riskRebuild
+	case before.name == after.name:
+		// 日期时间类型的小数位数
+		if after.arg(0) < before.arg(0) {
+			return riskLossy
+		}
+		return riskRebuild
+	}
+	bRank, bIsTime := timeTypeRank[before.name]
+	aRank, aIsTime := timeTypeRank[after.name]
+	// timestamp 只能表示 1970 到 2038 年
+	if bIsTime && aIsTime && aRank > bRank && bRank > 0 && after.name != "timestamp" {
+		return riskRebuild
+	}
+	return riskLossy
+}
+
+// 转换为这些字符集不会丢失字符
+var lossless4Charset = map[string][]string{
+	"utf8mb4": {"utf8", "utf8mb3", "latin1", "ascii", "gbk", "gb2312", "gb18030", "big5"},
+	"utf8mb3": {"utf8", "ascii"},
+	"utf8":    {"utf8mb3", "ascii"},
+}
+
+// columnChangeRisk 修改字段定义的风险及原因
+func columnChangeRisk(ch *schemaChange) (riskLevel, string) {
+	beforeName := ch.Name
+	if len(ch.From) > 0 {
+		beforeName = ch.From
+	}
+	before := fieldDefinition(beforeName, ch.Before)
+	after := fieldDefinition(ch.Name, ch.After)
+	if before == after || onlyDefaultChanged(&schemaChange{Before: before, After: after}) {
+		return riskSafe, ""
+	}
+	bt, at := parseColumnType(ch.Before), parseColumnType(ch.After)
+	risk := riskRebuild
+	var reasons []string
+	if tr := typeRisk(bt, at); tr != riskSafe {
+		risk = max(risk, tr)
+		reasons = append(reasons, bt.String()+" -> "+at.String())
+	}
+	if bt.charset != at.charset && len(at.charset) > 0 {
+		cr := riskLossy
+		if len(bt.charset) == 0 || inStringSlice(bt.charset, lossless4Charset[at.charset]) {
+			cr = riskRebuild
+		}
+		risk = max(risk, cr)
+		reasons = append(reasons, fmt.Sprintf("CHARACTER SET %s -> %s", bt.charset, at.charset))
+	}
+	if !bt.notNull && at.notNull {
+		risk = riskLossy
+		reasons = append(reasons, "NULL -> NOT NULL")
+	}
+	return risk, strings.Join(reasons, ", ")
+}
+
+// indexRisk 索引变更的风险：主键、全文索引、空间索引需要重建表
+func indexRisk(def string) riskLevel {
+	if strings.HasPrefix(def, "PRIMARY") || strings.HasPrefix(def, "FULLTEXT") || strings.HasPrefix(def, "SPATIAL") {
+		return riskRebuild
+	}
+	return riskSafe
+}
+
+// isUniqueIndex 唯一索引或主键
+func isUniqueIndex(def string) bool {
+	return strings.HasPrefix(def, "UNIQUE") || strings.HasPrefix(def, "PRIMARY")
+}
+
+// uniqueIndexRisk 新增唯一索引、主键时已有数据可能重复导致变更失败：
+// 只包含新增字段的索引不涉及已有数据，原来就是唯一索引且字段是新索引的子集时不会有新的重复
+func uniqueIndexRisk(sd *TableAlterData, ch *schemaChange) (riskLevel, string) {
+	risk := indexRisk(ch.After)
+	if !isUniqueIndex(ch.After) {
+		return risk, ""
+	}
+	after := indexColumnExprs(ch.After)
+	if len(ch.Before) > 0 && isUniqueIndex(ch.Before) {
+		before := indexColumnExprs(ch.Before)
+		if len(before) > 0 && len(after) > 0 && len(missingColumns(before, after)) == 0 {
+			return risk, ""
+		}
+	}
+	added := make(map[string]bool)
+	for _, c := range sd.Changes {
+		if c.Kind == changeColumnAdd {
+			added[c.Name] = true
+		}
+	}
+	existing := len(after) == 0
+	for _, expr := range after {
+		if !added[strings.Split(expr, "`")[1]] {
+			existing = true
+		}
+	}
+	if !existing {
+		return risk, ""
+	}
+	return riskLossy, "existing rows may have duplicates"
+}
+
+// changeRisk 单项变更的风险及原因
+func changeRisk(sd *TableAlterData, ch *schemaChange) (riskLevel, string) {
+	switch ch.Kind {
+	case changeTableCreate, changeTableRename, changeForeignDrop:
+		return riskSafe, ""
+	case changeTableDrop, changeColumnDrop:
+		return riskDestructive, ""
+	case changeTableOptions:
+		options := func(line string) string {
+			return strings.TrimSpace(strings.TrimPrefix(line, ")"))
+		}
+		return riskRebuild, options(ch.Before) + " -> " + options(ch.After)
+	case changeColumnAdd:
+		return riskRebuild, ""
+	case changeColumnChange, changeColumnRename:
+		return columnChangeRisk(ch)
+	case changeIndexAdd, changeIndexReplace:
+		return uniqueIndexRisk(sd, ch)
+	case changeIndexDrop:
+		return indexRisk(ch.Before), ""
+	case changeForeignAdd, changeForeignReplace:
+		// 新增外键时需要检查已有数据，使用 COPY 重建表，已有数据不满足外键时变更失败
+		return riskLossy, "existing rows may violate the foreign key"
+	}
+	return riskLossy, ""
+}
+
+// classifyRisk 评估表的每项变更的风险，返回最高的风险等级
+func classifyRisk(sd *TableAlterData) riskLevel {
+	for _, ch := range sd.Changes {
+		ch.Risk, ch.RiskReason = changeRisk(sd, ch)
+		if ch.Kind == changeTableDrop && len(sd.SQL) > 0 && renameTableReg.MatchString(sd.SQL[0]) {
+			// drop_table_backup 时重命名为备份表
+			ch.Risk, ch.RiskReason = riskSafe, "rename to backup table"
+		}
+	}
+	return sd.risk()
+}
+
+// risk 变更项中最高的风险等级
+func (ta *TableAlterData) risk() riskLevel {
+	level := riskSafe
+	for _, ch := range ta.Changes {
+		level = max(level, ch.Risk)
+	}
+	return level
+}
+
+// riskAllowed 风险不超过 -max_risk，或 names 中任一名称在 -allow 中，支持通配符
+func (cfg *Config) riskAllowed(risk riskLevel, names ...string) bool {
+	maxRisk, limited := parseRiskLevel(cfg.MaxRisk)
+	if !limited || risk <= maxRisk {
+		return true
+	}
+	for _, pattern := range cfg.Allow {
+		for _, name := range names {
+			if simpleMatch(pattern, name, "riskAllow") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// riskBlocked 风险超过 -max_risk 且不在 -allow 中的变更，-allow 为表名或 表名.字段名/索引名，支持通配符
+func (cfg *Config) riskBlocked(sd *TableAlterData) []*schemaChange {
+	var blocked []*schemaChange
+	for _, ch := range sd.Changes {
+		if !cfg.riskAllowed(ch.Risk, sd.Table, sd.Table+"."+ch.Name) {
+			blocked = append(blocked, ch)
+		}
+	}
+	return blocked
+}
+
+// blockDrop 删除存储过程、视图、触发器等对象的风险为 destructive，超过 -max_risk 且不在 -allow 中时 -sync 不执行，
+// names 为 -allow 中可以使用的名称
+func (cfg *Config) blockDrop(rec *routineRecord, names ...string) {
+	if len(rec.SQL) == 0 || cfg.riskAllowed(riskDestructive, names...) {
+		return
+	}
+	rec.Blocked = true
+	rec.Comment = fmt.Sprintf("风险 %s 超过 -max_risk=%s，-sync 时不执行，确认后使用 -allow %s 允许", riskDestructive, cfg.MaxRisk, names[0])
+}
+
+// riskComment 预览中变更风险的注释
+func riskComment(sd *TableAlterData, level riskLevel) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "-- risk: %s\n", level)
+	for _, ch := range sd.Changes {
+		if ch.Risk == riskSafe {
+			continue
+		}
+		fmt.Fprintf(&buf, "--   %s %s `%s`", ch.Risk, ch.Kind, ch.Name)
+		if len(ch.RiskReason) > 0 {
+			buf.WriteString(": " + ch.RiskReason)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
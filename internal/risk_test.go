@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_typeRisk(t *testing.T) {
+	tests := []struct {
+		before string
+		after  string
+		want   riskLevel
+	}{
+		{"`a` int(10) unsigned", "`a` int unsigned", riskSafe},
+		{"`a` int", "`a` bigint", riskRebuild},
+		{"`a` bigint", "`a` int", riskLossy},
+		{"`a` int", "`a` int unsigned", riskLossy},
+		{"`a` varchar(50)", "`a` varchar(255)", riskRebuild},
+		{"`a` varchar(255)", "`a` varchar(50)", riskLossy},
+		{"`a` varchar(255)", "`a` text", riskRebuild},
+		{"`a` text", "`a` varchar(255)", riskLossy},
+		{"`a` varchar(255)", "`a` varbinary(255)", riskLossy},
+		{"`a` decimal(10,2)", "`a` decimal(12,2)", riskRebuild},
+		{"`a` decimal(10,2)", "`a` decimal(10,1)", riskLossy},
+		{"`a` double", "`a` float", riskLossy},
+		{"`a` enum('a','b')", "`a` enum('a','b','c')", riskSafe},
+		{"`a` enum('a','b')", "`a` enum('b','a')", riskRebuild},
+		{"`a` enum('a','b,c')", "`a` enum('a')", riskLossy},
+		{"`a` datetime(3)", "`a` datetime", riskLossy},
+		{"`a` date", "`a` datetime", riskRebuild},
+		{"`a` datetime", "`a` timestamp", riskLossy},
+		{"`a` date", "`a` timestamp", riskLossy},
+		{"`a` int", "`a` varchar(20)", riskLossy},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, typeRisk(parseColumnType(tt.before), parseColumnType(tt.after)), tt.before+" -> "+tt.after)
+	}
+	require.Equal(t, []string{"'a'", "'b,c'", "'d\\'e'"}, parseColumnType("`a` enum('a','b,c','d\\'e') NOT NULL").args)
+}
+
+func Test_columnChangeRisk(t *testing.T) {
+	risk, reason := columnChangeRisk(&schemaChange{
+		Kind:   changeColumnChange,
+		Name:   "a",
+		Before: "`a` int NOT NULL DEFAULT '0'",
+		After:  "`a` int NOT NULL DEFAULT '1' COMMENT 'a'",
+	})
+	require.Equal(t, riskSafe, risk)
+	require.Empty(t, reason)
+
+	risk, reason = columnChangeRisk(&schemaChange{
+		Kind:   changeColumnRename,
+		Name:   "b",
+		From:   "a",
+		Before: "`a` varchar(255) DEFAULT NULL",
+		After:  "`b` varchar(255) DEFAULT NULL",
+	})
+	require.Equal(t, riskSafe, risk)
+	require.Empty(t, reason)
+
+	risk, reason = columnChangeRisk(&schemaChange{
+		Kind:   changeColumnChange,
+		Name:   "a",
+		Before: "`a` varchar(255) CHARACTER SET latin1 DEFAULT NULL",
+		After:  "`a` varchar(50) CHARACTER SET utf8mb4 NOT NULL",
+	})
+	require.Equal(t, riskLossy, risk)
+	require.Equal(t, "varchar(255) -> varchar(50), CHARACTER SET latin1 -> utf8mb4, NULL -> NOT NULL", reason)
+}
+
+func Test_classifyRisk(t *testing.T) {
+	sc := &SchemaSync{
+		Config: &Config{Drop: true},
+	}
+	sd := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_1.sql"), testLoadFile("testdata/user_2.sql"), &Config{})
+	require.Equal(t, riskDestructive, classifyRisk(sd))
+	var risks []riskLevel
+	for _, ch := range sd.Changes {
+		risks = append(risks, ch.Risk)
+	}
+	require.Equal(t, []riskLevel{riskRebuild, riskRebuild, riskDestructive, riskDestructive, riskDestructive}, risks)
+	require.Equal(t, "-- risk: destructive\n"+
+		"--   rebuild column_change `id`: int(10) unsigned -> bigint unsigned\n"+
+		"--   rebuild column_change `email`: varchar(100) -> varchar(1000)\n"+
+		"--   destructive column_drop `register_time`\n"+
+		"--   destructive column_drop `password`\n"+
+		"--   destructive column_drop `status`\n", riskComment(sd, riskDestructive))
+
+	cfg := &Config{}
+	require.Empty(t, cfg.riskBlocked(sd))
+	cfg.MaxRisk = "rebuild"
+	require.Len(t, cfg.riskBlocked(sd), 3)
+	cfg.Allow = []string{"user.password", "user.status"}
+	require.Equal(t, "register_time", cfg.riskBlocked(sd)[0].Name)
+	cfg.Allow = []string{"us*"}
+	require.Empty(t, cfg.riskBlocked(sd))
+
+	cfg = &Config{MaxRisk: "lossy"}
+	require.True(t, cfg.riskAllowed(riskLossy, "user"))
+	require.False(t, cfg.riskAllowed(riskDestructive, "user"))
+
+	drop := &TableAlterData{
+		Table:   "t",
+		Type:    alterTypeDropTable,
+		SQL:     []string{"RENAME TABLE `t` TO `t_dropped_20260101`;"},
+		Changes: []*schemaChange{{Kind: changeTableDrop, Name: "t"}},
+	}
+	require.Equal(t, riskSafe, classifyRisk(drop))
+}
+
+func TestConfig_blockDrop(t *testing.T) {
+	newRec := func() *routineRecord {
+		return &routineRecord{Name: "trg_log", SQL: []string{"DROP TRIGGER IF EXISTS `trg_log`"}}
+	}
+	rec := newRec()
+	(&Config{}).blockDrop(rec, rec.Name, "order")
+	require.False(t, rec.Blocked)
+
+	cfg := &Config{MaxRisk: "lossy"}
+	rec = newRec()
+	cfg.blockDrop(rec, rec.Name, "order")
+	require.True(t, rec.Blocked)
+	require.Equal(t, "风险 destructive 超过 -max_risk=lossy，-sync 时不执行，确认后使用 -allow trg_log 允许", rec.Comment)
+
+	// 可以使用所属的表名
+	cfg.Allow = []string{"ord*"}
+	rec = newRec()
+	cfg.blockDrop(rec, rec.Name, "order")
+	require.False(t, rec.Blocked)
+
+	// 没有 -drop 时只有提示，没有语句
+	rec = &routineRecord{Name: "p_old", SQL: []string{}}
+	(&Config{MaxRisk: "safe"}).blockDrop(rec, rec.Name)
+	require.False(t, rec.Blocked)
+}
+
+func Test_uniqueIndexRisk(t *testing.T) {
+	sd := &TableAlterData{Changes: []*schemaChange{
+		{Kind: changeColumnAdd, Name: "code", After: "`code` varchar(20) DEFAULT NULL"},
+	}}
+	tests := []struct {
+		kind   changeKind
+		before string
+		after  string
+		want   riskLevel
+	}{
+		{changeIndexAdd, "", "KEY `idx_email` (`email`)", riskSafe},
+		{changeIndexAdd, "", "UNIQUE KEY `uk_email` (`email`)", riskLossy},
+		{changeIndexAdd, "", "PRIMARY KEY (`id`)", riskLossy},
+		{changeIndexAdd, "", "UNIQUE KEY `uk_code` (`code`)", riskSafe},
+		{changeIndexAdd, "", "UNIQUE KEY `uk_code` (`code`,`email`)", riskLossy},
+		{changeIndexReplace, "UNIQUE KEY `uk_email` (`email`)", "UNIQUE KEY `uk_email` (`email`,`name`)", riskSafe},
+		{changeIndexReplace, "UNIQUE KEY `uk_email` (`email`,`name`)", "UNIQUE KEY `uk_email` (`email`)", riskLossy},
+		{changeIndexReplace, "KEY `uk_email` (`email`)", "UNIQUE KEY `uk_email` (`email`)", riskLossy},
+		{changeForeignAdd, "", "CONSTRAINT `fk_uid` FOREIGN KEY (`uid`) REFERENCES `user` (`id`)", riskLossy},
+	}
+	for _, tt := range tests {
+		risk, _ := changeRisk(sd, &schemaChange{Kind: tt.kind, Name: "x", Before: tt.before, After: tt.after})
+		require.Equal(t, tt.want, risk, tt.before+" -> "+tt.after)
+	}
+}
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// rollbackItem 一个对象的回滚语句，delimiter 为 true 时使用 DELIMITER $$ 输出（存储过程、触发器）
+type rollbackItem struct {
+	title     string
+	comment   string
+	sqls      []string
+	delimiter bool
+}
+
+type rollbackSchema struct {
+	schema string
+	items  []*rollbackItem
+}
+
+// rollbackScript 收集同步语句的逆操作，按与同步相反的顺序输出为 sql 文件
+type rollbackScript struct {
+	schemas []*rollbackSchema
+}
+
+var rollback = &rollbackScript{}
+
+func (rs *rollbackScript) beginSchema(schema string) {
+	rs.schemas = append(rs.schemas, &rollbackSchema{schema: schema})
+}
+
+func (rs *rollbackScript) add(item *rollbackItem) {
+	if len(rs.schemas) == 0 || (len(item.sqls) == 0 && len(item.comment) == 0) {
+		return
+	}
+	current := rs.schemas[len(rs.schemas)-1]
+	current.items = append(current.items, item)
+}
+
+func (rs *rollbackScript) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "-- rollback script generated by mysql-sync %s at %s\n", Version, time.Now().Format(timeFormatStd))
+	for _, s := range rs.schemas {
+		if len(s.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "\nUSE `%s`;\n", s.schema)
+		for i := len(s.items) - 1; i >= 0; i-- {
+			item := s.items[i]
+			buf.WriteString("\n-- " + item.title + "\n")
+			if len(item.comment) > 0 {
+				buf.WriteString("-- " + item.comment + "\n")
+			}
+			if len(item.sqls) == 0 {
+				continue
+			}
+			if item.delimiter {
+				fmt.Fprintf(&buf, "DELIMITER $$\n%s$$\nDELIMITER ;\n", strings.Join(item.sqls, "$$\n"))
+				continue
+			}
+			for _, sql := range item.sqls {
+				buf.WriteString(strings.TrimRight(sql, ";") + ";\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+// WriteRollback 将回滚语句写入 -rollback 指定的文件
+func WriteRollback() {
+	if len(rollbackPath) == 0 {
+		return
+	}
+	if err := os.WriteFile(rollbackPath, []byte(rollback.String()), 0644); err != nil {
+		log.Println("write rollback script failed:", err)
+		return
+	}
+	log.Println("rollback script saved:", rollbackPath)
+}
+
+var rollbackPath string
+
+func init() {
+	flag.StringVar(&rollbackPath, "rollback", "", "file path to save the rollback sql of changes")
+}
+
+var renameTableReg = regexp.MustCompile("^RENAME TABLE `(.+)` TO `(.+)`;?$")
+
+// addTableRollback 记录表结构变更的逆操作
+func (rs *rollbackScript) addTableRollback(sd *TableAlterData) {
+	if len(sd.SQL) == 0 {
+		return
+	}
+	item := &rollbackItem{title: "Table : " + sd.Table}
+	switch sd.Type {
+	case alterTypeCreate:
+		item.sqls = []string{fmt.Sprintf("DROP TABLE IF EXISTS `%s`", sd.Table)}
+	case alterTypeRename:
+		item.sqls = []string{fmt.Sprintf("RENAME TABLE `%s` TO `%s`", sd.Table, sd.Changes[0].Before)}
+	case alterTypeDropTable:
+		if m := renameTableReg.FindStringSubmatch(sd.SQL[0]); m != nil {
+			item.sqls = []string{fmt.Sprintf("RENAME TABLE `%s` TO `%s`", m[2], m[1])}
+		} else {
+			item.comment = "表已删除，只能恢复表结构，数据无法恢复"
+			item.sqls = []string{fmtTableCreateSQL(sd.Changes[0].Before)}
+		}
+	case alterTypeAlter:
+		item.comment = droppedColumnsComment(sd)
+		item.sqls = alterRollbackSQL(sd)
+	}
+	rs.add(item)
+}
+
+// droppedColumnsComment 删除的字段只能恢复定义，重新添加的字段为空值或默认值
+func droppedColumnsComment(sd *TableAlterData) string {
+	var columns []string
+	for _, ch := range sd.Changes {
+		if ch.Kind == changeColumnDrop {
+			columns = append(columns, "`"+ch.Name+"`")
+		}
+	}
+	if len(columns) == 0 {
+		return ""
+	}
+	return "字段 " + strings.Join(columns, ",") + " 已删除，只能恢复字段定义，数据无法恢复"
+}
+
+// alterRollbackSQL 由每项变更的逆变更生成恢复目标库原结构的 ALTER 语句
+func alterRollbackSQL(sd *TableAlterData) []string {
+	var inverses []*schemaChange
+	for _, ch := range sd.Changes {
+		if inv := ch.inverse(); inv != nil {
+			inverses = append(inverses, inv)
+		}
+	}
+	return alterTableSQL(sd.Table, inverses, false)
+}
+
+// addObjectRollback 记录存储过程、函数、事件、视图、触发器变更的逆操作
+func (rs *rollbackScript) addObjectRollback(title string, rec *routineRecord, delimiter bool) {
+	if len(rec.SQL) == 0 || rec.Blocked {
+		return
+	}
+	rs.add(&rollbackItem{title: title + " : " + rec.Name, sqls: rec.undo, delimiter: delimiter})
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_alterRollbackSQL(t *testing.T) {
+	sc := &SchemaSync{
+		Config: &Config{Drop: true},
+	}
+	sd := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_1.sql"), testLoadFile("testdata/user_2.sql"), &Config{})
+	require.Equal(t, []string{"ALTER TABLE `user`\n" +
+		"CHANGE `id` `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"CHANGE `email` `email` varchar(100) NOT NULL DEFAULT '',\n" +
+		"ADD `register_time` timestamp NOT NULL AFTER `email`,\n" +
+		"ADD `password` varchar(255) NOT NULL DEFAULT '' AFTER `register_time`,\n" +
+		"ADD `status` int(10) unsigned NOT NULL DEFAULT 1 AFTER `password`;",
+	}, alterRollbackSQL(sd))
+
+	sd = sc.getAlterDataBySchema("user", testLoadFile("testdata/user_6.sql"), testLoadFile("testdata/user_1.sql"), &Config{})
+	require.Equal(t, []string{"ALTER TABLE `user`\n" +
+		"drop `register_time`,\n" +
+		"drop `password`,\n" +
+		"drop `status`,\n" +
+		"DROP INDEX `idx_email`;",
+	}, alterRollbackSQL(sd))
+}
+
+func Test_rollbackScript(t *testing.T) {
+	rs := &rollbackScript{}
+	rs.beginSchema("db1")
+	rs.addTableRollback(&TableAlterData{
+		Table: "t_new",
+		Type:  alterTypeCreate,
+		SQL:   []string{"CREATE TABLE `t_new` (`id` int);"},
+	})
+	rs.addTableRollback(&TableAlterData{
+		Table: "t_old",
+		Type:  alterTypeDropTable,
+		SQL:   []string{"RENAME TABLE `t_old` TO `t_old_dropped_20260101`;"},
+	})
+	// 没有执行语句的变更不需要回滚
+	rs.addTableRollback(&TableAlterData{Table: "t_keep", Type: alterTypeDropTable})
+	rs.addTableRollback(&TableAlterData{
+		Table: "t_alter",
+		Type:  alterTypeAlter,
+		SQL:   []string{"ALTER TABLE `t_alter`\ndrop `a`,\ndrop `b`;"},
+		Changes: []*schemaChange{
+			{Kind: changeColumnDrop, Name: "a", Before: "`a` int"},
+			{Kind: changeColumnDrop, Name: "b", Before: "`b` int", Position: "AFTER `a`"},
+		},
+	})
+	rs.addObjectRollback("Procedure", &routineRecord{
+		Name: "p",
+		SQL:  []string{"DROP PROCEDURE IF EXISTS `p`", "CREATE PROCEDURE `p`() BEGIN SELECT 2; END"},
+		undo: []string{"DROP PROCEDURE IF EXISTS `p`", "CREATE PROCEDURE `p`() BEGIN SELECT 1; END"},
+	}, true)
+	rs.beginSchema("db2")
+
+	script := rs.String()
+	require.True(t, strings.HasPrefix(script, "-- rollback script generated by mysql-sync"))
+	_, body, _ := strings.Cut(script, "\n")
+	require.Equal(t, "\nUSE `db1`;\n"+
+		"\n-- Procedure : p\nDELIMITER $$\nDROP PROCEDURE IF EXISTS `p`$$\nCREATE PROCEDURE `p`() BEGIN SELECT 1; END$$\nDELIMITER ;\n"+
+		"\n-- Table : t_alter\n-- 字段 `a`,`b` 已删除，只能恢复字段定义，数据无法恢复\n"+
+		"ALTER TABLE `t_alter`\nADD `a` int,\nADD `b` int AFTER `a`;\n"+
+		"\n-- Table : t_old\nRENAME TABLE `t_old_dropped_20260101` TO `t_old`;\n"+
+		"\n-- Table : t_new\nDROP TABLE IF EXISTS `t_new`;\n", body)
+	require.Len(t, splitSQLScript(body), 6)
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// routineKind 存储程序的类型，值与 show create xxx 中的关键字一致
+type routineKind string
+
+const (
+	routineProcedure routineKind = "procedure"
+	routineFunction  routineKind = "function"
+	routineEvent     routineKind = "event"
+)
+
+var routineKinds = []routineKind{routineProcedure, routineFunction, routineEvent}
+
+// title 首字母大写的类型名，用于 sql 注释
+func (kind routineKind) title() string {
+	return strings.ToUpper(string(kind[:1])) + string(kind[1:])
+}
+
+func (kind routineKind) dropSQL(name string) string {
+	return fmt.Sprintf("DROP %s IF EXISTS `%s`", strings.ToUpper(string(kind)), name)
+}
+
+// CheckAlterRoutine 对比存储过程、函数和事件，
+// 源库新增或变化的先删除再重建，目标库多余的在 drop 时删除，否则只输出提示
+func CheckAlterRoutine(cfg *Config) {
+	for _, kind := range routineKinds {
+		checkAlterRoutine(cfg, kind)
+	}
+}
+
+// stripDefiner 去除存储过程、函数、事件、触发器定义中的 DEFINER，目标库使用执行同步的用户作为 DEFINER
+// CREATE DEFINER=`root`@`%` PROCEDURE `p_clean`() BEGIN ... END
+func stripDefiner(schema string) string {
+	schema = viewClauseRegs[viewClauseDefiner].ReplaceAllString(schema, "")
+	return strings.TrimSpace(schema)
+}
+
+// loadRoutines 读取库中按 procedures、procedures_ignore 过滤后的存储程序，返回名称和 名称 => 定义
+func (sc *SchemaSync) loadRoutines(db *MyDb, kind routineKind, cfg *Config) ([]string, map[string]string) {
+	var names []string
+	schemas := make(map[string]string)
+	for _, name := range db.GetRoutineNames(kind) {
+		if !cfg.CheckMatchRoutines(name) {
+			continue
+		}
+		schema := db.GetRoutineSchema(kind, name, true)
+		if db == sc.SourceDb {
+			schema = sc.rewriteSchemaRefs(schema)
+		}
+		names = append(names, name)
+		schemas[name] = stripDefiner(schema)
+	}
+	return names, schemas
+}
+
+// diffRoutines 源库新增或变化的先删除再重建
+func diffRoutines(kind routineKind, srcNames []string, srcSchemas map[string]string, dstSchemas map[string]string) []*routineRecord {
+	var changes []*routineRecord
+	for _, name := range srcNames {
+		srcSchema, dstSchema := srcSchemas[name], dstSchemas[name]
+		if srcSchema == dstSchema {
+			continue
+		}
+		undo := []string{kind.dropSQL(name)}
+		if len(dstSchema) > 0 {
+			undo = append(undo, dstSchema)
+		}
+		changes = append(changes, &routineRecord{
+			Name: name,
+			SQL:  []string{kind.dropSQL(name), srcSchema},
+			undo: undo,
+		})
+	}
+	return changes
+}
+
+// destOnlyRoutines 目标库多余的存储程序，drop 时删除，否则只输出提示
+func destOnlyRoutines(kind routineKind, dstNames []string, srcSchemas map[string]string, dstSchemas map[string]string, drop bool) []*routineRecord {
+	var changes []*routineRecord
+	for _, name := range dstNames {
+		if _, has := srcSchemas[name]; has {
+			continue
+		}
+		rec := &routineRecord{
+			Name:    name,
+			Comment: "源数据库不存在，删除目标数据库多余的" + string(kind),
+			SQL:     []string{kind.dropSQL(name)},
+			undo:    []string{dstSchemas[name]},
+		}
+		if !drop {
+			rec.Comment = "源数据库不存在，使用 -drop 删除目标数据库多余的" + string(kind)
+			rec.SQL = []string{}
+		}
+		changes = append(changes, rec)
+	}
+	return changes
+}
+
+// printRoutine 预览中输出存储过程、函数、事件的变更
+func (o *resultOutput) printRoutine(kind routineKind, rec *routineRecord) {
+	if len(rec.SQL) == 0 {
+		o.printf("-- %s `%s` : %s\n\n", kind, rec.Name, rec.Comment)
+		return
+	}
+	if rec.Blocked {
+		o.printf("-- %s `%s` : %s\n", kind, rec.Name, rec.Comment)
+	}
+	// 不支持直接执行语句 DELIMITER $$
+	o.printf("DELIMITER $$\n%s$$\nDELIMITER ;\n\n", strings.Join(rec.SQL, "$$\n"))
+}
+
+func checkAlterRoutine(cfg *Config, kind routineKind) {
+	sc := NewSchemaSync(cfg)
+	srcNames, srcSchemas := sc.loadRoutines(sc.SourceDb, kind, cfg)
+	dstNames, dstSchemas := sc.loadRoutines(sc.DestDb, kind, cfg)
+
+	changes := diffRoutines(kind, srcNames, srcSchemas, dstSchemas)
+	for _, rec := range destOnlyRoutines(kind, dstNames, srcSchemas, dstSchemas, cfg.Drop) {
+		cfg.blockDrop(rec, rec.Name)
+		changes = append(changes, rec)
+	}
+
+	for _, rec := range changes {
+		output.printRoutine(kind, rec)
+		output.addRoutine(kind, rec)
+		if len(rec.SQL) == 0 {
+			continue
+		}
+		rollback.addObjectRollback(kind.title(), rec, true)
+
+		// 直接执行同步
+		if !sc.Config.Sync || rec.Blocked {
+			continue
+		}
+		if err := executor.exec(sc.DestDb, rec.SQL); err != nil && err != errExecStopped {
+			log.Println("exec", kind, "failed", rec.Name, err)
+			drift.execErrors++
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_stripDefiner(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{
+			"CREATE DEFINER=`root`@`%` PROCEDURE `p_clean`()\nBEGIN\n  DELETE FROM `log`;\nEND",
+			"CREATE PROCEDURE `p_clean`()\nBEGIN\n  DELETE FROM `log`;\nEND",
+		},
+		{
+			"CREATE DEFINER=`app`@`localhost` FUNCTION `f_add`(a int, b int) RETURNS int\n    DETERMINISTIC\nRETURN a + b\n",
+			"CREATE FUNCTION `f_add`(a int, b int) RETURNS int\n    DETERMINISTIC\nRETURN a + b",
+		},
+		{
+			"CREATE DEFINER=`root`@`%` EVENT `e_clean` ON SCHEDULE EVERY 1 DAY STARTS '2024-01-01 00:00:00' ON COMPLETION NOT PRESERVE ENABLE DO DELETE FROM `log`",
+			"CREATE EVENT `e_clean` ON SCHEDULE EVERY 1 DAY STARTS '2024-01-01 00:00:00' ON COMPLETION NOT PRESERVE ENABLE DO DELETE FROM `log`",
+		},
+		{
+			"CREATE DEFINER=`app`@`10.0.0.%` TRIGGER `trg_upd` BEFORE UPDATE ON `user` FOR EACH ROW SET NEW.updated = NOW()\n",
+			"CREATE TRIGGER `trg_upd` BEFORE UPDATE ON `user` FOR EACH ROW SET NEW.updated = NOW()",
+		},
+		{
+			"CREATE TRIGGER `trg_upd` BEFORE UPDATE ON `user` FOR EACH ROW SET NEW.updated = NOW()",
+			"CREATE TRIGGER `trg_upd` BEFORE UPDATE ON `user` FOR EACH ROW SET NEW.updated = NOW()",
+		},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, stripDefiner(tt.schema))
+	}
+	require.Equal(t, "DROP EVENT IF EXISTS `e_clean`", routineEvent.dropSQL("e_clean"))
+}
+
+func Test_diffRoutines(t *testing.T) {
+	srcSchemas := map[string]string{"p_a": "CREATE PROCEDURE `p_a`() v1", "p_b": "CREATE PROCEDURE `p_b`() v2", "p_c": "CREATE PROCEDURE `p_c`() v1"}
+	dstSchemas := map[string]string{"p_a": "CREATE PROCEDURE `p_a`() v1", "p_b": "CREATE PROCEDURE `p_b`() v1", "p_d": "CREATE PROCEDURE `p_d`() v1"}
+
+	changes := diffRoutines(routineProcedure, []string{"p_a", "p_b", "p_c"}, srcSchemas, dstSchemas)
+	require.Len(t, changes, 2)
+	require.Equal(t, "p_b", changes[0].Name)
+	require.Equal(t, []string{"DROP PROCEDURE IF EXISTS `p_b`", "CREATE PROCEDURE `p_b`() v2"}, changes[0].SQL)
+	require.Equal(t, []string{"DROP PROCEDURE IF EXISTS `p_b`", "CREATE PROCEDURE `p_b`() v1"}, changes[0].undo)
+	require.Equal(t, "p_c", changes[1].Name)
+	require.Equal(t, []string{"DROP PROCEDURE IF EXISTS `p_c`"}, changes[1].undo)
+}
+
+func Test_destOnlyRoutines(t *testing.T) {
+	srcSchemas := map[string]string{"f_a": "CREATE FUNCTION `f_a`() v1"}
+	dstSchemas := map[string]string{"f_a": "CREATE FUNCTION `f_a`() v1", "f_b": "CREATE FUNCTION `f_b`() v1"}
+
+	tests := []struct {
+		drop bool
+		sql  []string
+	}{
+		{false, []string{}},
+		{true, []string{"DROP FUNCTION IF EXISTS `f_b`"}},
+	}
+	for _, tt := range tests {
+		changes := destOnlyRoutines(routineFunction, []string{"f_a", "f_b"}, srcSchemas, dstSchemas, tt.drop)
+		require.Len(t, changes, 1)
+		require.Equal(t, "f_b", changes[0].Name)
+		require.Equal(t, tt.sql, changes[0].SQL)
+		require.Equal(t, []string{"CREATE FUNCTION `f_b`() v1"}, changes[0].undo)
+	}
+}
@@ -109,13 +109,19 @@ type SchemaDiff struct {
 	Source *MySchema
 	Dest   *MySchema
 	Table  string
+
+	// 完整的 show create table 结果，用于报告展示
+	SourceRaw string
+	DestRaw   string
 }
 
 func newSchemaDiff(table, source, dest string) *SchemaDiff {
 	return &SchemaDiff{
-		Table:  table,
-		Source: ParseSchema(source),
-		Dest:   ParseSchema(dest),
+		Table:     table,
+		Source:    ParseSchema(RemoveTableSchemaConfig(source)),
+		Dest:      ParseSchema(RemoveTableSchemaConfig(dest)),
+		SourceRaw: source,
+		DestRaw:   dest,
 	}
 }
 
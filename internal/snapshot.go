@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// snapshotScheme source、dest 以 file:// 开头时使用 -dump_schema 保存的快照文件代替数据库连接
+const snapshotScheme = "file://"
+
+// errSnapshotQuery 快照中只有结构，不能查询数据
+var errSnapshotQuery = errors.New("query is not supported on schema snapshot")
+
+// schemaSnapshot 数据库结构的快照，包含表、视图、存储过程、函数、事件和触发器的定义
+type schemaSnapshot struct {
+	Version string                     `json:"version"`
+	Schemas map[string]*snapshotSchema `json:"schemas"`
+}
+
+type snapshotSchema struct {
+	// Tables 表名 => show create table 的结果
+	Tables     map[string]string `json:"tables"`
+	Views      map[string]string `json:"views"`
+	Procedures map[string]string `json:"procedures"`
+	Functions  map[string]string `json:"functions"`
+	Events     map[string]string `json:"events"`
+	// Triggers 按表、触发时机、事件、执行顺序排列
+	Triggers []*snapshotTrigger `json:"triggers"`
+}
+
+type snapshotTrigger struct {
+	Name   string `json:"name"`
+	Table  string `json:"table"`
+	Schema string `json:"schema"`
+}
+
+func isSnapshotDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, snapshotScheme)
+}
+
+func (ss *snapshotSchema) routines(kind routineKind) map[string]string {
+	switch kind {
+	case routineFunction:
+		return ss.Functions
+	case routineEvent:
+		return ss.Events
+	default:
+		return ss.Procedures
+	}
+}
+
+// 已加载的快照文件，切换数据库时不重复读取
+var snapshotFiles = make(map[string]*schemaSnapshot)
+
+// newSnapshotDb 使用快照文件中的数据库 dbname，快照中只有一个数据库时也可以用于不同名的数据库
+func newSnapshotDb(dsn string, dbname string, dsnName string) *MyDb {
+	path := strings.TrimPrefix(dsn, snapshotScheme)
+	snapshot, has := snapshotFiles[path]
+	if !has {
+		if err := loadJSONFile(path, &snapshot); err != nil {
+			log.Fatalln("load schema snapshot failed:", path, err)
+		}
+		snapshotFiles[path] = snapshot
+	}
+	schema, has := snapshot.Schemas[dbname]
+	if !has && len(snapshot.Schemas) == 1 {
+		for _, only := range snapshot.Schemas {
+			schema = only
+		}
+	} else if !has {
+		log.Fatalln("schema", dbname, "not found in snapshot", path)
+	}
+	return &MyDb{
+		dbType:   dsnName,
+		DbName:   dbname,
+		snapshot: schema,
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dumpSchema 读取数据库当前的结构
+func dumpSchema(db *MyDb) *snapshotSchema {
+	ss := &snapshotSchema{
+		Tables:     make(map[string]string),
+		Views:      make(map[string]string),
+		Procedures: make(map[string]string),
+		Functions:  make(map[string]string),
+		Events:     make(map[string]string),
+		Triggers:   make([]*snapshotTrigger, 0),
+	}
+	for _, name := range db.GetTableNames() {
+		ss.Tables[name] = db.GetTableSchema(name)
+	}
+	for _, name := range db.GetViewNames() {
+		ss.Views[name] = db.GetViewSchema(name)
+	}
+	for _, kind := range routineKinds {
+		for _, name := range db.GetRoutineNames(kind) {
+			ss.routines(kind)[name] = db.GetRoutineSchema(kind, name, true)
+		}
+	}
+	names, tables := db.GetTriggerNames()
+	for _, name := range names {
+		ss.Triggers = append(ss.Triggers, &snapshotTrigger{Name: name, Table: tables[name], Schema: db.GetTriggerSchema(name)})
+	}
+	return ss
+}
+
+// DumpSchema 将源库中 schemas 配置的数据库结构保存为快照文件
+func DumpSchema(cfg *Config, path string) {
+	if len(cfg.SourceDSN) == 0 {
+		log.Fatal("source DSN is empty")
+	}
+	if len(cfg.Schemas) <= 0 {
+		log.Fatal("Schemas is empty")
+	}
+	snapshot := &schemaSnapshot{
+		Version: Version,
+		Schemas: make(map[string]*snapshotSchema),
+	}
+	for _, schema := range cfg.Schemas {
+		srcName, _ := cfg.splitSchema(schema)
+		db := NewMyDb(cfg.SourceDSN, srcName, "source", cfg.SourceSSH)
+		snapshot.Schemas[srcName] = dumpSchema(db)
+		log.Println("dump schema", srcName, "tables:", len(snapshot.Schemas[srcName].Tables))
+	}
+	bs, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		log.Fatalln("marshal schema snapshot failed:", err)
+	}
+	if err := os.WriteFile(path, append(bs, '\n'), 0644); err != nil {
+		log.Fatalln("write schema snapshot failed:", err)
+	}
+	log.Println("schema snapshot saved:", path)
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testWriteSnapshot(t *testing.T, snapshot *schemaSnapshot) string {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	bs, err := json.MarshalIndent(snapshot, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, bs, 0644))
+	return snapshotScheme + path
+}
+
+func Test_newSnapshotDb(t *testing.T) {
+	dsn := testWriteSnapshot(t, &schemaSnapshot{
+		Schemas: map[string]*snapshotSchema{
+			"test": {
+				Tables: map[string]string{
+					"user":  testLoadFile("testdata/user_1.sql"),
+					"order": "CREATE TABLE `order` (\n  `id` int NOT NULL\n) ENGINE=InnoDB",
+				},
+				Procedures: map[string]string{"p1": "CREATE PROCEDURE `p1`() BEGIN END"},
+				Triggers: []*snapshotTrigger{
+					{Name: "tr_user", Table: "user", Schema: "CREATE TRIGGER `tr_user` BEFORE INSERT ON `user` FOR EACH ROW SET NEW.id = 1"},
+				},
+			},
+		},
+	})
+	require.True(t, isSnapshotDSN(dsn))
+
+	db := NewMyDb(dsn, "test", "source", "")
+	require.Equal(t, []string{"order", "user"}, db.GetTableNames())
+	require.Equal(t, testLoadFile("testdata/user_1.sql"), db.GetTableSchema("user"))
+	require.Empty(t, db.GetTableSchema("none"))
+	require.Equal(t, []string{"p1"}, db.GetRoutineNames(routineProcedure))
+	require.Empty(t, db.GetRoutineNames(routineFunction))
+	names, tables := db.GetTriggerNames()
+	require.Equal(t, []string{"tr_user"}, names)
+	require.Equal(t, "user", tables["tr_user"])
+	require.Contains(t, db.GetTriggerSchema("tr_user"), "BEFORE INSERT ON `user`")
+	_, err := db.Query("select 1")
+	require.ErrorIs(t, err, errSnapshotQuery)
+
+	// 快照中只有一个数据库时可以用于不同名的数据库
+	require.Len(t, NewMyDb(dsn, "test_dev", "dest", "").GetTableNames(), 2)
+}
+
+func Test_snapshotAlterData(t *testing.T) {
+	source := testWriteSnapshot(t, &schemaSnapshot{
+		Schemas: map[string]*snapshotSchema{
+			"a": {Tables: map[string]string{"user": testLoadFile("testdata/user_1.sql")}},
+			"b": {},
+		},
+	})
+	dest := testWriteSnapshot(t, &schemaSnapshot{
+		Schemas: map[string]*snapshotSchema{
+			"a": {Tables: map[string]string{"user": testLoadFile("testdata/user_2.sql")}},
+		},
+	})
+	cfg := &Config{SourceDSN: source, DestDSN: dest, Schemas: []string{"a"}}
+	sc := &SchemaSync{
+		Config:   cfg,
+		SourceDb: NewMyDb(source, "a", "source", ""),
+		DestDb:   NewMyDb(dest, "a", "dest", ""),
+	}
+	require.Empty(t, sc.GetNewTableNames())
+
+	sd := sc.getAlterDataByTable("user", cfg)
+	require.Equal(t, alterTypeAlter, sd.Type)
+	want := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_1.sql"), testLoadFile("testdata/user_2.sql"), cfg)
+	require.Equal(t, want.SQL, sd.SQL)
+	require.Nil(t, sc.preflight(sd, &Config{Preflight: true}))
+}
@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// sqlResult 一条查询的结果，字段按查询中的顺序
+type sqlResult struct {
+	Columns []string
+	Rows    [][]sql.NullString
+}
+
+// sqlCellChange 按 key 对应的两行中值不同的字段
+type sqlCellChange struct {
+	Key    []string `json:"key"`
+	Column string   `json:"column"`
+	Source string   `json:"source"`
+	Dest   string   `json:"dest"`
+}
+
+// sqlResultDiff 两个库查询结果的差异
+type sqlResultDiff struct {
+	Keys       []string         `json:"keys,omitempty"`
+	OnlySource [][]string       `json:"only_source,omitempty"`
+	OnlyDest   [][]string       `json:"only_dest,omitempty"`
+	Changed    []*sqlCellChange `json:"changed,omitempty"`
+}
+
+func (d *sqlResultDiff) hasDiff() bool {
+	return len(d.OnlySource)+len(d.OnlyDest)+len(d.Changed) > 0
+}
+
+func (d *sqlResultDiff) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# diff: only in source %d, only in dest %d, changed cells %d",
+		len(d.OnlySource), len(d.OnlyDest), len(d.Changed))
+	for _, row := range d.OnlySource {
+		fmt.Fprintf(&buf, "\n# < (%s)", strings.Join(row, ", "))
+	}
+	for _, row := range d.OnlyDest {
+		fmt.Fprintf(&buf, "\n# > (%s)", strings.Join(row, ", "))
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&buf, "\n# ~ (%s) %s: %s => %s", strings.Join(c.Key, ", "), c.Column, c.Source, c.Dest)
+	}
+	return buf.String()
+}
+
+// cellString 字段值的展示形式，NULL 不加引号以便和字符串 'NULL' 区分，非 utf8 的二进制数据使用十六进制
+func cellString(val sql.NullString) string {
+	if !val.Valid {
+		return "NULL"
+	}
+	if !utf8.ValidString(val.String) {
+		return "0x" + hex.EncodeToString([]byte(val.String))
+	}
+	return val.String
+}
+
+// cellLiteral 差异中使用 sql 字面量，区分 NULL 与字符串
+func cellLiteral(val sql.NullString) string {
+	if !val.Valid {
+		return "NULL"
+	}
+	return sqlLiteral([]byte(val.String))
+}
+
+func rowStrings(row []sql.NullString, format func(sql.NullString) string) []string {
+	strs := make([]string, len(row))
+	for i, v := range row {
+		strs[i] = format(v)
+	}
+	return strs
+}
+
+// rowKey 多个字段值拼接为 map 的 key，NULL 与空字符串不同
+func rowKey(row []sql.NullString, idx []int) string {
+	var buf strings.Builder
+	for _, i := range idx {
+		if row[i].Valid {
+			buf.WriteString("v")
+			buf.WriteString(row[i].String)
+		} else {
+			buf.WriteString("n")
+		}
+		buf.WriteByte(0)
+	}
+	return buf.String()
+}
+
+// queryResult 执行查询，值按文本协议读取，保留数据库返回的格式
+func queryResult(db *MyDb, query string) (*sqlResult, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &sqlResult{Columns: columns}
+	for rows.Next() {
+		row := make([]sql.NullString, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
+
+// queryBoth 同时在源库和目标库执行查询
+func (sc *SchemaSync) queryBoth(query string) (src *sqlResult, dst *sqlResult, err error) {
+	var srcErr, dstErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		src, srcErr = queryResult(sc.SourceDb, query)
+	}()
+	go func() {
+		defer wg.Done()
+		dst, dstErr = queryResult(sc.DestDb, query)
+	}()
+	wg.Wait()
+	if srcErr != nil {
+		return nil, nil, fmt.Errorf("query on source failed: %w", srcErr)
+	}
+	if dstErr != nil {
+		return nil, nil, fmt.Errorf("query on dest failed: %w", dstErr)
+	}
+	return src, dst, nil
+}
+
+func printSQLResult(result *sqlResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		fmt.Fprintln(w, strings.Join(rowStrings(row, cellString), "\t"))
+	}
+	w.Flush()
+	fmt.Printf("(%d rows)\n", len(result.Rows))
+}
+
+// diffSQLResult 对比两个查询结果，keys 为空时按整行对比（重复的行按次数计算），
+// 否则按 keys 对应的行逐个字段对比
+func diffSQLResult(src *sqlResult, dst *sqlResult, keys []string) (*sqlResultDiff, error) {
+	if strings.Join(src.Columns, ",") != strings.Join(dst.Columns, ",") {
+		return nil, fmt.Errorf("columns not same, source: (%s), dest: (%s)",
+			strings.Join(src.Columns, ", "), strings.Join(dst.Columns, ", "))
+	}
+	diff := &sqlResultDiff{Keys: keys}
+
+	if len(keys) == 0 {
+		all := make([]int, len(src.Columns))
+		for i := range all {
+			all[i] = i
+		}
+		counts := make(map[string]int)
+		for _, row := range dst.Rows {
+			counts[rowKey(row, all)]++
+		}
+		for _, row := range src.Rows {
+			k := rowKey(row, all)
+			if counts[k] > 0 {
+				counts[k]--
+				continue
+			}
+			diff.OnlySource = append(diff.OnlySource, rowStrings(row, cellLiteral))
+		}
+		for _, row := range dst.Rows {
+			k := rowKey(row, all)
+			if counts[k] > 0 {
+				counts[k]--
+				diff.OnlyDest = append(diff.OnlyDest, rowStrings(row, cellLiteral))
+			}
+		}
+		return diff, nil
+	}
+
+	idx := make([]int, len(keys))
+	for i, key := range keys {
+		idx[i] = indexOf(src.Columns, key)
+		if idx[i] < 0 {
+			return nil, fmt.Errorf("key column `%s` not in result", key)
+		}
+	}
+	index := func(result *sqlResult, name string) (map[string][]sql.NullString, error) {
+		m := make(map[string][]sql.NullString, len(result.Rows))
+		for _, row := range result.Rows {
+			k := rowKey(row, idx)
+			if _, has := m[k]; has {
+				return nil, fmt.Errorf("duplicate key (%s) in %s result", strings.Join(keyStrings(row, idx), ", "), name)
+			}
+			m[k] = row
+		}
+		return m, nil
+	}
+	srcRows, err := index(src, "source")
+	if err != nil {
+		return nil, err
+	}
+	dstRows, err := index(dst, "dest")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range src.Rows {
+		dstRow, has := dstRows[rowKey(row, idx)]
+		if !has {
+			diff.OnlySource = append(diff.OnlySource, rowStrings(row, cellLiteral))
+			continue
+		}
+		for i, col := range src.Columns {
+			if row[i] != dstRow[i] {
+				diff.Changed = append(diff.Changed, &sqlCellChange{
+					Key:    keyStrings(row, idx),
+					Column: col,
+					Source: cellLiteral(row[i]),
+					Dest:   cellLiteral(dstRow[i]),
+				})
+			}
+		}
+	}
+	for _, row := range dst.Rows {
+		if _, has := srcRows[rowKey(row, idx)]; !has {
+			diff.OnlyDest = append(diff.OnlyDest, rowStrings(row, cellLiteral))
+		}
+	}
+	return diff, nil
+}
+
+func keyStrings(row []sql.NullString, idx []int) []string {
+	strs := make([]string, len(idx))
+	for i, j := range idx {
+		strs[i] = cellLiteral(row[j])
+	}
+	return strs
+}
+
+// CompareSqlResult 在两个库执行同一条查询，输出结果及差异，结果一致时返回 true
+func CompareSqlResult(cfg *Config, query string, keys []string) bool {
+	sc := NewSchemaSync(cfg)
+
+	src, dst, err := sc.queryBoth(query)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Println("# sql result on sourceDSN")
+	printSQLResult(src)
+	fmt.Println("# sql result on destDSN")
+	printSQLResult(dst)
+
+	diff, err := diffSQLResult(src, dst, keys)
+	if err != nil {
+		fmt.Println("# diff failed:", err)
+		return false
+	}
+	if !diff.hasDiff() {
+		fmt.Println("# sql result is same")
+		return true
+	}
+	fmt.Println(diff)
+	return false
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testSQLResult(columns []string, rows ...[]any) *sqlResult {
+	result := &sqlResult{Columns: columns}
+	for _, row := range rows {
+		vals := make([]sql.NullString, len(row))
+		for i, v := range row {
+			if v != nil {
+				vals[i] = sql.NullString{String: v.(string), Valid: true}
+			}
+		}
+		result.Rows = append(result.Rows, vals)
+	}
+	return result
+}
+
+func Test_diffSQLResult(t *testing.T) {
+	columns := []string{"club_id", "gold", "remark"}
+	src := testSQLResult(columns, []any{"1", "100", nil}, []any{"2", "200", "a"}, []any{"3", "300", "b"})
+	dst := testSQLResult(columns, []any{"1", "100", "NULL"}, []any{"2", "250", "a"}, []any{"4", "400", ""})
+
+	diff, err := diffSQLResult(src, dst, []string{"club_id"})
+	require.NoError(t, err)
+	require.True(t, diff.hasDiff())
+	require.Equal(t, [][]string{{"'3'", "'300'", "'b'"}}, diff.OnlySource)
+	require.Equal(t, [][]string{{"'4'", "'400'", "''"}}, diff.OnlyDest)
+	require.Equal(t, []*sqlCellChange{
+		{Key: []string{"'1'"}, Column: "remark", Source: "NULL", Dest: "'NULL'"},
+		{Key: []string{"'2'"}, Column: "gold", Source: "'200'", Dest: "'250'"},
+	}, diff.Changed)
+	require.Equal(t, "# diff: only in source 1, only in dest 1, changed cells 2\n# < ('3', '300', 'b')\n# > ('4', '400', '')\n"+
+		"# ~ ('1') remark: NULL => 'NULL'\n# ~ ('2') gold: '200' => '250'", diff.String())
+
+	// 按整行对比，重复的行按次数计算
+	src = testSQLResult(columns, []any{"1", "100", nil}, []any{"1", "100", nil})
+	dst = testSQLResult(columns, []any{"1", "100", nil}, []any{"2", "200", nil})
+	diff, err = diffSQLResult(src, dst, nil)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"'1'", "'100'", "NULL"}}, diff.OnlySource)
+	require.Equal(t, [][]string{{"'2'", "'200'", "NULL"}}, diff.OnlyDest)
+	require.Empty(t, diff.Changed)
+
+	diff, err = diffSQLResult(src, src, nil)
+	require.NoError(t, err)
+	require.False(t, diff.hasDiff())
+
+	_, err = diffSQLResult(src, dst, []string{"club_id"})
+	require.Error(t, err)
+	_, err = diffSQLResult(src, testSQLResult([]string{"gold"}), nil)
+	require.Error(t, err)
+}
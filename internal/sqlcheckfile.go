@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sqlCheck 一条命名的校验查询，keys 为空时按整行对比结果
+type sqlCheck struct {
+	Name string   `yaml:"name" json:"name"`
+	SQL  string   `yaml:"sql" json:"sql"`
+	Keys []string `yaml:"keys" json:"keys,omitempty"`
+}
+
+// sqlCheckResult 一条校验查询的结果
+type sqlCheckResult struct {
+	*sqlCheck
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	SourceRows int            `json:"source_rows"`
+	DestRows   int            `json:"dest_rows"`
+	Diff       *sqlResultDiff `json:"diff,omitempty"`
+	timer      *myTimer
+}
+
+// sqlCheckReg sql 格式的校验文件中的注释：-- name: xxx、-- keys: a,b
+var sqlCheckReg = regexp.MustCompile(`^--\s*(name|keys)\s*:\s*(.*)$`)
+
+// parseSQLChecks 解析 sql 格式的校验文件，每条查询以 -- name: 开头，可以用 -- keys: 指定匹配行的字段
+func parseSQLChecks(text string) ([]*sqlCheck, error) {
+	var checks []*sqlCheck
+	var current *sqlCheck
+	var body []string
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		current.SQL = strings.TrimRight(strings.TrimSpace(strings.Join(body, "\n")), ";")
+		if len(current.SQL) == 0 {
+			return fmt.Errorf("sql of check `%s` is empty", current.Name)
+		}
+		checks = append(checks, current)
+		return nil
+	}
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		match := sqlCheckReg.FindStringSubmatch(strings.TrimSpace(line))
+		switch {
+		case match != nil && match[1] == "name":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current = &sqlCheck{Name: strings.TrimSpace(match[2])}
+			body = nil
+		case match != nil && current != nil && len(body) == 0:
+			for _, key := range strings.Split(match[2], ",") {
+				if key = strings.TrimSpace(key); len(key) > 0 {
+					current.Keys = append(current.Keys, key)
+				}
+			}
+		case current != nil:
+			body = append(body, line)
+		case len(strings.TrimSpace(line)) > 0 && !strings.HasPrefix(strings.TrimSpace(line), "--"):
+			return nil, fmt.Errorf("line %d: sql without `-- name:`", i+1)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// loadSQLChecks 读取校验文件，.yaml/.yml 为 yaml 格式，其他为 sql 格式
+func loadSQLChecks(path string) []*sqlCheck {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalln("load sql check file failed:", err)
+	}
+	var checks []*sqlCheck
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(bs, &checks)
+	default:
+		checks, err = parseSQLChecks(string(bs))
+	}
+	if err != nil {
+		log.Fatalln("parse sql check file failed:", path, err)
+	}
+	names := make(map[string]bool)
+	for _, c := range checks {
+		if len(c.Name) == 0 || len(strings.TrimSpace(c.SQL)) == 0 {
+			log.Fatalln("name and sql of check are necessary:", path)
+		}
+		if names[c.Name] {
+			log.Fatalln("duplicate check name:", c.Name)
+		}
+		names[c.Name] = true
+	}
+	return checks
+}
+
+func (sc *SchemaSync) runSQLCheck(check *sqlCheck) *sqlCheckResult {
+	ret := &sqlCheckResult{sqlCheck: check, Status: "pass", timer: newMyTimer()}
+	defer ret.timer.stop()
+
+	src, dst, err := sc.queryBoth(check.SQL)
+	if err == nil {
+		ret.SourceRows, ret.DestRows = len(src.Rows), len(dst.Rows)
+		ret.Diff, err = diffSQLResult(src, dst, check.Keys)
+	}
+	if err != nil {
+		ret.Status = "error"
+		ret.Error = err.Error()
+	} else if ret.Diff.hasDiff() {
+		ret.Status = "fail"
+	} else {
+		ret.Diff = nil
+	}
+	return ret
+}
+
+// CompareSqlFile 依次执行校验文件中的查询，每条查询同时在两个库执行，
+// 输出通过/失败的汇总表及失败查询的差异（json），全部通过时返回 true
+func CompareSqlFile(cfg *Config, path string) bool {
+	sc := NewSchemaSync(cfg)
+	checks := loadSQLChecks(path)
+
+	var results []*sqlCheckResult
+	for _, check := range checks {
+		results = append(results, sc.runSQLCheck(check))
+	}
+
+	fmt.Println("# sql check file:", path)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tSOURCE_ROWS\tDEST_ROWS\tUSED")
+	passed := 0
+	for _, ret := range results {
+		if ret.Status == "pass" {
+			passed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", ret.Name, ret.Status, ret.SourceRows, ret.DestRows, ret.timer.usedSecond())
+	}
+	w.Flush()
+	fmt.Printf("# %d passed, %d failed\n", passed, len(results)-passed)
+
+	for _, ret := range results {
+		if ret.Status == "pass" {
+			continue
+		}
+		bs, err := json.MarshalIndent(ret, "", "  ")
+		if err != nil {
+			log.Println("marshal check result failed:", err)
+			continue
+		}
+		fmt.Printf("\n# %s %s\n%s\n", ret.Status, ret.Name, bs)
+	}
+	return passed == len(results)
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_loadSQLChecks(t *testing.T) {
+	checks := loadSQLChecks("testdata/checks.sql")
+	require.Equal(t, []*sqlCheck{
+		{Name: "club_count", SQL: "select count(1) as cc from club"},
+		{
+			Name: "club_gold",
+			SQL:  "select club_id, day, sum(gold) as gold\nfrom club_log\n-- 只看最近7天\nwhere day > curdate() - interval 7 day\ngroup by club_id, day",
+			Keys: []string{"club_id", "day"},
+		},
+	}, checks)
+
+	checks = loadSQLChecks("testdata/checks.yaml")
+	require.Len(t, checks, 2)
+	require.Equal(t, "club_count", checks[0].Name)
+	require.Equal(t, []string{"club_id", "day"}, checks[1].Keys)
+}
+
+func Test_parseSQLChecks(t *testing.T) {
+	_, err := parseSQLChecks("select 1;\n-- name: a\nselect 2")
+	require.Error(t, err)
+
+	_, err = parseSQLChecks("-- name: a\n-- name: b\nselect 2")
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"strings"
+)
+
+// sqlSplitter 按 mysql 客户端的规则将 sql 脚本拆分为单条语句：
+// 引号、反引号中的内容和语句中的注释原样保留，以便存储过程、触发器的定义与源库一致；
+// 语句前的 -- 、# 、/* */ 注释被去掉，只有注释的语句不执行；/*! */ 可执行注释和 /*+ */ 优化器提示视为语句内容，
+// 支持 DELIMITER 修改语句结束符，以便执行本工具输出的存储过程、触发器语句
+type sqlSplitter struct {
+	script    string
+	pos       int
+	delimiter string
+	current   strings.Builder
+	// hasCode 当前语句已有注释以外的内容
+	hasCode bool
+	stmts   []string
+}
+
+// splitSQLScript 拆分 sql 脚本，返回的语句不包含结束符
+func splitSQLScript(script string) []string {
+	sp := &sqlSplitter{
+		script:    strings.ReplaceAll(script, "\r\n", "\n"),
+		delimiter: ";",
+	}
+	sp.run()
+	return sp.stmts
+}
+
+func (sp *sqlSplitter) flush() {
+	stmt := strings.TrimSpace(sp.current.String())
+	if sp.hasCode && len(stmt) > 0 {
+		sp.stmts = append(sp.stmts, stmt)
+	}
+	sp.current.Reset()
+	sp.hasCode = false
+}
+
+func (sp *sqlSplitter) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(sp.script[sp.pos:], prefix)
+}
+
+// restOfLine 当前位置到行尾（不含换行）的内容
+func (sp *sqlSplitter) restOfLine() string {
+	end := strings.IndexByte(sp.script[sp.pos:], '\n')
+	if end < 0 {
+		return sp.script[sp.pos:]
+	}
+	return sp.script[sp.pos : sp.pos+end]
+}
+
+// atStatementStart 当前语句还没有内容（只有空白和注释）
+func (sp *sqlSplitter) atStatementStart() bool {
+	return !sp.hasCode
+}
+
+// comment 语句中的注释原样保留，语句前的注释去掉
+func (sp *sqlSplitter) comment(next int) {
+	if sp.hasCode {
+		sp.current.WriteString(sp.script[sp.pos:next])
+	}
+	sp.pos = next
+}
+
+// delimiterCommand 语句开头的 DELIMITER xx 命令，返回新的结束符
+func (sp *sqlSplitter) delimiterCommand() (string, bool) {
+	fields := strings.Fields(sp.restOfLine())
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "delimiter") {
+		return "", false
+	}
+	return fields[1], true
+}
+
+func (sp *sqlSplitter) run() {
+	for sp.pos < len(sp.script) {
+		if sp.atStatementStart() {
+			if delimiter, ok := sp.delimiterCommand(); ok {
+				sp.delimiter = delimiter
+				sp.pos += len(sp.restOfLine())
+				sp.current.Reset()
+				continue
+			}
+		}
+
+		c := sp.script[sp.pos]
+		switch {
+		case sp.hasPrefix(sp.delimiter):
+			sp.pos += len(sp.delimiter)
+			sp.flush()
+		case c == '\'' || c == '"' || c == '`':
+			sp.quoted(c)
+		case c == '#' || sp.isDashComment():
+			// 注释到行尾，换行作为语句内容
+			sp.comment(sp.pos + len(sp.restOfLine()))
+		case sp.hasPrefix("/*!") || sp.hasPrefix("/*+"):
+			sp.hasCode = true
+			sp.comment(sp.blockCommentEnd())
+		case sp.hasPrefix("/*"):
+			sp.comment(sp.blockCommentEnd())
+		default:
+			if strings.IndexByte(" \t\n", c) < 0 {
+				sp.hasCode = true
+			}
+			sp.current.WriteByte(c)
+			sp.pos++
+		}
+	}
+	sp.flush()
+}
+
+// isDashComment -- 后需要跟空白字符或位于行尾才是注释
+func (sp *sqlSplitter) isDashComment() bool {
+	if !sp.hasPrefix("--") {
+		return false
+	}
+	next := sp.pos + 2
+	return next >= len(sp.script) || strings.IndexByte(" \t\n", sp.script[next]) >= 0
+}
+
+// quoted 读取引号中的内容，支持反斜杠转义（反引号除外）和连续两个引号的转义
+func (sp *sqlSplitter) quoted(quote byte) {
+	start := sp.pos
+	sp.pos++
+	for sp.pos < len(sp.script) {
+		c := sp.script[sp.pos]
+		if c == '\\' && quote != '`' {
+			sp.pos += 2
+			continue
+		}
+		sp.pos++
+		if c == quote {
+			if sp.pos < len(sp.script) && sp.script[sp.pos] == quote {
+				sp.pos++
+				continue
+			}
+			break
+		}
+	}
+	sp.pos = min(sp.pos, len(sp.script))
+	sp.current.WriteString(sp.script[start:sp.pos])
+	sp.hasCode = true
+}
+
+// blockCommentEnd /* */ 注释结束后的位置
+func (sp *sqlSplitter) blockCommentEnd() int {
+	end := strings.Index(sp.script[sp.pos+2:], "*/")
+	if end < 0 {
+		return len(sp.script)
+	}
+	return sp.pos + 2 + end + 2
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitSQLScript(t *testing.T) {
+	script := "-- Table : user\r\n" +
+		"ALTER TABLE `user` ADD `a;b` varchar(10) DEFAULT 'x;\\'y' COMMENT \"c;\"\"d\";\r\n" +
+		"# comment; with semicolon\n" +
+		"/* block; comment */ UPDATE t SET v = '--not comment' WHERE id = 1 ; " +
+		"SELECT /*+ MAX_EXECUTION_TIME(1000) */ 1;\n" +
+		"/*!40101 SET NAMES utf8mb4 */;\n" +
+		"SELECT 1--1;\n" +
+		"DELIMITER $$\n" +
+		"CREATE PROCEDURE `p`()\nBEGIN\n  SELECT 1;\n  SELECT ';$$';\nEND$$\n" +
+		"DROP TRIGGER IF EXISTS `tr`$$\n" +
+		"DELIMITER ;\n" +
+		"SELECT 2"
+	require.Equal(t, []string{
+		"ALTER TABLE `user` ADD `a;b` varchar(10) DEFAULT 'x;\\'y' COMMENT \"c;\"\"d\"",
+		"UPDATE t SET v = '--not comment' WHERE id = 1",
+		"SELECT /*+ MAX_EXECUTION_TIME(1000) */ 1",
+		"/*!40101 SET NAMES utf8mb4 */",
+		"SELECT 1--1",
+		"CREATE PROCEDURE `p`()\nBEGIN\n  SELECT 1;\n  SELECT ';$$';\nEND",
+		"DROP TRIGGER IF EXISTS `tr`",
+		"SELECT 2",
+	}, splitSQLScript(script))
+
+	require.Empty(t, splitSQLScript("-- only comment\n;;\n/* block */;\n# hash\n"))
+
+	// 语句中的注释原样保留，存储过程导入后与源库定义一致
+	body := "CREATE PROCEDURE `p`()\nBEGIN\n  -- clean; old rows\n  DELETE FROM `log`; # hash; comment\n  /* block; */ SELECT 1;\nEND"
+	require.Equal(t, []string{body, "SELECT 1 -- tail"},
+		splitSQLScript("-- p\nDELIMITER $$\n"+body+"$$\nDELIMITER ;\n-- lead\nSELECT 1 -- tail\n;"))
+}
+
+// 本工具输出的预览可以直接用 -sql_file 导入
+func Test_splitSQLScript_preview(t *testing.T) {
+	var buf strings.Builder
+	saved := output
+	output = &resultOutput{format: formatText, w: &buf}
+	defer func() { output = saved }()
+
+	sc := &SchemaSync{Config: &Config{Drop: true}}
+	alter := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_1.sql"), testLoadFile("testdata/user_2.sql"), &Config{})
+	create := sc.getAlterDataBySchema("order", "CREATE TABLE `order` (\n  `id` int NOT NULL\n) ENGINE=InnoDB", "", &Config{})
+	classifyRisk(alter)
+	proc := &routineRecord{Name: "p1", SQL: []string{
+		"DROP PROCEDURE IF EXISTS `p1`",
+		"CREATE PROCEDURE `p1`()\nBEGIN\n  -- clean; old rows\n  DELETE FROM `log`;\nEND",
+	}}
+	trigger := &routineRecord{Name: "tr_user", Comment: "changed, dropped before create", SQL: []string{
+		"DROP TRIGGER IF EXISTS `tr_user`",
+		"CREATE TRIGGER `tr_user` BEFORE INSERT ON `user` FOR EACH ROW BEGIN SET NEW.email = ''; END",
+	}}
+	view := &routineRecord{Name: "v_user", SQL: []string{"CREATE OR REPLACE VIEW `v_user` AS select `id` from `user`"}}
+
+	output.beginSchema(sc.Config, "a", "a")
+	output.println(alter)
+	output.printf("%s", riskComment(alter, alter.risk()))
+	output.println("")
+	output.printRoutine(routineProcedure, proc)
+	output.printTrigger(trigger)
+	output.printView(view)
+	output.beginSchema(sc.Config, "a", "b")
+	output.println(create)
+	output.println("")
+	output.printRoutine(routineFunction, &routineRecord{Name: "f1", Comment: "only in dest"})
+
+	var want []string
+	for _, stmt := range alter.SQL {
+		want = append(want, strings.TrimSuffix(stmt, ";"))
+	}
+	want = append(want, proc.SQL...)
+	want = append(want, trigger.SQL...)
+	want = append(want, view.SQL[0], strings.TrimSuffix(create.SQL[0], ";"))
+	require.Equal(t, want, splitSQLScript(buf.String()))
+}
@@ -1,12 +1,19 @@
 package internal
 
 import (
+	"bytes"
 	"flag"
+	"html/template"
+	"log"
+	"os"
+	"strings"
+	"time"
 )
 
 type statics struct {
 	timer  *myTimer
 	Config *Config
+	schema string
 	tables []*tableStatics
 }
 
@@ -16,13 +23,20 @@ type tableStatics struct {
 	alter       *TableAlterData
 	alterRet    error
 	schemaAfter string
+	executed    bool
+	// blocked 风险超过 -max_risk，-sync 时不执行
+	blocked bool
 }
 
-func newStatics(cfg *Config) *statics {
+// 已完成对比的所有数据库，用于生成 html 报告
+var staticsAll []*statics
+
+func newStatics(cfg *Config, schema string) *statics {
 	return &statics{
 		timer:  newMyTimer(),
 		tables: make([]*tableStatics, 0),
 		Config: cfg,
+		schema: schema,
 	}
 }
 
@@ -35,20 +49,204 @@ func (s *statics) newTableStatics(table string, sd *TableAlterData, index int) *
 	if sd.Type == alterTypeNo {
 		return ts
 	}
-	if s.Config.SingleSchemaChange {
+	if s.Config.SingleSchemaChange && len(sd.SQL) > 0 {
 		sds := sd.Split()
 		nts := &tableStatics{}
 		*nts = *ts
 		nts.alter = sds[index]
 		s.tables = append(s.tables, nts)
-	} else {
-		s.tables = append(s.tables, ts)
+		return nts
 	}
+	s.tables = append(s.tables, ts)
 	return ts
 }
 
+func (ts *tableStatics) status() string {
+	if ts.blocked {
+		return "blocked"
+	}
+	if !ts.executed {
+		return "preview"
+	}
+	if ts.alterRet != nil {
+		return "failed"
+	}
+	return "success"
+}
+
+// 一张表在 html 报告中的展示数据
+type htmlTable struct {
+	Table       string
+	Type        string
+	Risk        string
+	Comment     string
+	SQL         string
+	Status      string
+	Error       string
+	Used        string
+	Diff        []diffRow
+	SchemaAfter string
+}
+
+type htmlResult struct {
+	Version string
+	Time    string
+	Sync    bool
+	Drop    bool
+	Source  string
+	Dest    string
+	Total   int
+	Schemas []*htmlSchema
+}
+
+type htmlSchema struct {
+	Schema string
+	Used   string
+	Tables []*htmlTable
+}
+
+func (s *statics) toHTMLSchema() *htmlSchema {
+	hs := &htmlSchema{
+		Schema: s.schema,
+		Used:   s.timer.usedSecond(),
+	}
+	for _, ts := range s.tables {
+		ht := &htmlTable{
+			Table:   ts.table,
+			Type:    ts.alter.Type.String(),
+			Risk:    ts.alter.risk().String(),
+			Comment: ts.alter.Comment,
+			SQL:     ts.alter.String(),
+			Status:  ts.status(),
+			Used:    ts.timer.usedSecond(),
+		}
+		if ts.executed {
+			ht.SchemaAfter = ts.schemaAfter
+		}
+		if ts.alterRet != nil {
+			ht.Error = ts.alterRet.Error()
+		}
+		if ts.alter.SchemaDiff != nil {
+			ht.Diff = sideBySideDiff(ts.alter.SchemaDiff.SourceRaw, ts.alter.SchemaDiff.DestRaw)
+		}
+		hs.Tables = append(hs.Tables, ht)
+	}
+	return hs
+}
+
+// writeHTMLResult 将所有数据库的对比结果写入 html 文件
+func writeHTMLResult(cfg *Config) {
+	if len(htmlResultPath) == 0 {
+		return
+	}
+	data := &htmlResult{
+		Version: Version,
+		Time:    time.Now().Format(timeFormatStd),
+		Sync:    cfg.Sync,
+		Drop:    cfg.Drop,
+		Source:  maskDSN(cfg.SourceDSN),
+		Dest:    maskDSN(cfg.DestDSN),
+	}
+	for _, s := range staticsAll {
+		hs := s.toHTMLSchema()
+		data.Total += len(hs.Tables)
+		data.Schemas = append(data.Schemas, hs)
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTpl.Execute(&buf, data); err != nil {
+		log.Println("render html result failed:", err)
+		return
+	}
+	if err := os.WriteFile(htmlResultPath, buf.Bytes(), 0644); err != nil {
+		log.Println("write html result failed:", err)
+		return
+	}
+	log.Println("html result saved:", htmlResultPath)
+}
+
+// maskDSN 隐藏 dsn 中的密码
+func maskDSN(dsn string) string {
+	at := strings.LastIndex(dsn, "@")
+	colon := strings.Index(dsn, ":")
+	if at < 0 || colon < 0 || colon > at {
+		return dsn
+	}
+	return dsn[:colon] + ":***" + dsn[at:]
+}
+
 func init() {
 	flag.StringVar(&htmlResultPath, "html", "", "html result file path")
 }
 
 var htmlResultPath string
+
+var htmlTpl = template.Must(template.New("result").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>mysql-sync result</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;font-size:14px;margin:20px;color:#333}
+h1{font-size:22px}
+h2{font-size:18px;border-bottom:2px solid #3a7bd5;padding-bottom:4px;margin-top:32px}
+h3{font-size:15px;margin:0}
+table{border-collapse:collapse}
+.info td{padding:2px 12px 2px 0}
+.table{border:1px solid #ddd;border-radius:4px;margin:16px 0;padding:10px}
+.meta span{display:inline-block;margin-right:16px;color:#666}
+.status-success{color:#2e7d32;font-weight:bold}
+.status-failed{color:#c62828;font-weight:bold}
+.status-preview{color:#1565c0;font-weight:bold}
+.status-blocked{color:#ef6c00;font-weight:bold}
+.risk-lossy{color:#ef6c00;font-weight:bold}
+.risk-destructive{color:#c62828;font-weight:bold}
+.error{background:#fdecea;color:#c62828;padding:6px;white-space:pre-wrap}
+pre{background:#f6f8fa;padding:8px;overflow:auto;margin:6px 0}
+.diff{width:100%;table-layout:fixed;font-family:Consolas,monospace;font-size:12px}
+.diff th{background:#eee;text-align:left;padding:4px}
+.diff td{padding:1px 4px;white-space:pre-wrap;word-break:break-all;vertical-align:top;border-left:1px solid #ddd}
+.diff .change td{background:#fff8c5}
+.diff .delete td.l{background:#ffebe9}
+.diff .insert td.r{background:#e6ffec}
+</style>
+</head>
+<body>
+<h1>mysql-sync result</h1>
+<table class="info">
+<tr><td>version</td><td>{{.Version}}</td></tr>
+<tr><td>time</td><td>{{.Time}}</td></tr>
+<tr><td>source</td><td>{{.Source}}</td></tr>
+<tr><td>dest</td><td>{{.Dest}}</td></tr>
+<tr><td>sync</td><td>{{.Sync}}</td></tr>
+<tr><td>drop</td><td>{{.Drop}}</td></tr>
+<tr><td>changes</td><td>{{.Total}}</td></tr>
+</table>
+{{range .Schemas}}
+<h2>db {{.Schema}} <small>({{len .Tables}} changes, used {{.Used}})</small></h2>
+{{if not .Tables}}<p>no schema changes</p>{{end}}
+{{range .Tables}}
+<div class="table">
+<h3>{{.Table}}</h3>
+<div class="meta">
+<span>type: {{.Type}}</span>
+<span>risk: <span class="risk-{{.Risk}}">{{.Risk}}</span></span>
+<span>status: <span class="status-{{.Status}}">{{.Status}}</span></span>
+<span>used: {{.Used}}</span>
+{{if .Comment}}<span>{{.Comment}}</span>{{end}}
+</div>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+<table class="diff">
+<tr><th>source</th><th>dest</th></tr>
+{{range .Diff}}<tr class="{{.Kind}}"><td class="l">{{.Left}}</td><td class="r">{{.Right}}</td></tr>
+{{end}}</table>
+<div>SQL:</div>
+<pre>{{.SQL}}</pre>
+{{if .SchemaAfter}}<div>schema after sync:</div>
+<pre>{{.SchemaAfter}}</pre>{{end}}
+</div>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_statics_toHTMLSchema(t *testing.T) {
+	sc := &SchemaSync{Config: &Config{}}
+	s := newStatics(&Config{}, "test")
+	sd := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_1.sql"), testLoadFile("testdata/user_2.sql"), &Config{})
+	classifyRisk(sd)
+
+	// -max_risk 拦截的表同样出现在报告中
+	st := s.newTableStatics(sd.Table, sd, 0)
+	st.blocked = true
+	st.executed = true
+	hs := s.toHTMLSchema()
+	require.Len(t, hs.Tables, 1)
+	require.Equal(t, "blocked", hs.Tables[0].Status)
+	require.Equal(t, sd.risk().String(), hs.Tables[0].Risk)
+	require.Equal(t, sd.String(), hs.Tables[0].SQL)
+
+	// 不删除的目标库多余的表只有说明没有语句
+	sc.DestDb = &MyDb{snapshot: &snapshotSchema{}}
+	for _, cfg := range []*Config{{}, {SingleSchemaChange: true}} {
+		s = newStatics(cfg, "test")
+		sd = sc.getAlterDataBySchema("log", "", testLoadFile("testdata/user_1.sql"), cfg)
+		sc.setDropTableSQL(sd, cfg)
+		s.newTableStatics(sd.Table, sd, 0).timer.stop()
+		hs = s.toHTMLSchema()
+		require.Len(t, hs.Tables, 1)
+		require.Equal(t, "log", hs.Tables[0].Table)
+		require.Equal(t, "preview", hs.Tables[0].Status)
+		require.Equal(t, "源数据库不存在，使用 -drop 删除目标数据库多余的表", hs.Tables[0].Comment)
+	}
+}
@@ -5,8 +5,7 @@ import (
 	"log"
 	"regexp"
 	"strings"
-
-	"github.com/jmoiron/sqlx"
+	"time"
 )
 
 // SchemaSync 配置文件
@@ -14,6 +13,9 @@ type SchemaSync struct {
 	Config   *Config
 	SourceDb *MyDb
 	DestDb   *MyDb
+
+	// 当前数据库中数据有差异的表
+	dataDiffs []*tableDataDiff
 }
 
 var syncInstance *SchemaSync
@@ -23,22 +25,43 @@ func NewSchemaSync(config *Config) *SchemaSync {
 	if syncInstance == nil {
 		syncInstance = new(SchemaSync)
 		syncInstance.Config = config
-		syncInstance.SourceDb = NewMyDb(config.SourceDSN, config.Schemas[0], "source", config.SourceSSH)
-		syncInstance.DestDb = NewMyDb(config.DestDSN, config.Schemas[0], "dest", config.DestSSH)
+		srcName, dstName := config.splitSchema(config.Schemas[0])
+		syncInstance.SourceDb = NewMyDb(config.SourceDSN, srcName, "source", config.SourceSSH)
+		syncInstance.DestDb = NewMyDb(config.DestDSN, dstName, "dest", config.DestSSH)
 	}
 	return syncInstance
 }
 
-// use dbName
+// use dbName, 源数据库和目标数据库名字不同时使用 源库名:目标库名
 func (sc *SchemaSync) UseDb(dbname string) {
+	srcName, dstName := sc.Config.splitSchema(dbname)
 
-	if sc.SourceDb.DbName != dbname {
-		syncInstance.SourceDb = NewMyDb(sc.Config.SourceDSN, dbname, "source", sc.Config.SourceSSH)
+	if sc.SourceDb.DbName != srcName {
+		syncInstance.SourceDb = NewMyDb(sc.Config.SourceDSN, srcName, "source", sc.Config.SourceSSH)
 	}
-	if sc.DestDb.DbName != dbname {
-		syncInstance.DestDb = NewMyDb(sc.Config.DestDSN, dbname, "dest", sc.Config.DestSSH)
+	if sc.DestDb.DbName != dstName {
+		syncInstance.DestDb = NewMyDb(sc.Config.DestDSN, dstName, "dest", sc.Config.DestSSH)
 	}
-	fmt.Printf("------------------------ db %s -------------------------\n", dbname)
+	output.beginSchema(sc.Config, srcName, dstName)
+	rollback.beginSchema(dstName)
+}
+
+// schemaRefReg 定义中带库名的引用：`库名`.
+var schemaRefReg = regexp.MustCompile("`([^`]+)`\\.")
+
+// rewriteSchemaRefs 将源数据库定义中对其他数据库的引用（外键、视图等）替换为目标数据库中对应的库名，
+// 一次替换所有引用，a:b、b:c 同时存在时 a 只替换为 b
+func (sc *SchemaSync) rewriteSchemaRefs(schema string) string {
+	refs := sc.Config.schemaRefMap()
+	if len(refs) == 0 {
+		return schema
+	}
+	return schemaRefReg.ReplaceAllStringFunc(schema, func(ref string) string {
+		if dst, has := refs[ref[1:len(ref)-2]]; has {
+			return "`" + dst + "`."
+		}
+		return ref
+	})
 }
 
 // GetNewTableNames 获取所有新增加的表名
@@ -49,19 +72,21 @@ func (sc *SchemaSync) GetNewTableNames() []string {
 	var newTables []string
 
 	for _, name := range sourceTables {
-		if !inStringSlice(name, destTables) {
+		if !inStringSlice(sc.Config.destTableName(name), destTables) {
 			newTables = append(newTables, name)
 		}
 	}
 	return newTables
 }
 
-// 合并源数据库和目标数据库的表名
+// 合并源数据库和目标数据库的表名，目标数据库的表名按 table_map 转换为源数据库的表名
 func (sc *SchemaSync) GetTableNames() []string {
 	sourceTables := sc.SourceDb.GetTableNames()
 	destTables := sc.DestDb.GetTableNames()
 	var tables []string
-	tables = append(tables, destTables...)
+	for _, name := range destTables {
+		tables = append(tables, sc.Config.sourceTableName(name))
+	}
 	for _, name := range sourceTables {
 		if !inStringSlice(name, tables) {
 			tables = append(tables, name)
@@ -76,17 +101,22 @@ func RemoveTableSchemaConfig(schema string) string {
 	return strings.Split(schema, "ENGINE")[0]
 }
 
+// getAlterDataByTable 对比源数据库的表 table 和目标数据库中对应的表（按 table_map 映射）
 func (sc *SchemaSync) getAlterDataByTable(table string, cfg *Config) *TableAlterData {
-	sSchema := sc.SourceDb.GetTableSchema(table)
-	dSchema := sc.DestDb.GetTableSchema(table)
-	return sc.getAlterDataBySchema(table, sSchema, dSchema, cfg)
+	destTable := cfg.destTableName(table)
+	sSchema := sc.rewriteSchemaRefs(sc.SourceDb.GetTableSchema(table))
+	dSchema := sc.DestDb.GetTableSchema(destTable)
+	if destTable != table {
+		sSchema = renameTableSchema(sSchema, table, destTable)
+	}
+	return sc.getAlterDataBySchema(destTable, sSchema, dSchema, cfg)
 }
 
 func (sc *SchemaSync) getAlterDataBySchema(table string, sSchema string, dSchema string, cfg *Config) *TableAlterData {
 	alter := new(TableAlterData)
 	alter.Table = table
 	alter.Type = alterTypeNo
-	alter.SchemaDiff = newSchemaDiff(table, RemoveTableSchemaConfig(sSchema), RemoveTableSchemaConfig(dSchema))
+	alter.SchemaDiff = newSchemaDiff(table, sSchema, dSchema)
 
 	if sSchema == dSchema {
 		return alter
@@ -94,113 +124,120 @@ func (sc *SchemaSync) getAlterDataBySchema(table string, sSchema string, dSchema
 	if len(sSchema) == 0 {
 		alter.Type = alterTypeDropTable
 		alter.Comment = "源数据库不存在，删除目标数据库多余的表"
-		alter.SQL = append(alter.SQL, fmt.Sprintf("drop table `%s`;", table))
-		return alter
-	}
-	if len(dSchema) == 0 {
+		alter.addChange(changeTableDrop, table, dSchema, "")
+	} else if len(dSchema) == 0 {
 		alter.Type = alterTypeCreate
 		alter.Comment = "目标数据库不存在，创建"
-		alter.SQL = append(alter.SQL, fmtTableCreateSQL(sSchema)+";")
-		return alter
-	}
-
-	// 比对引擎和字符集
-	alterEng := sc.getTableEngDiff(table, sSchema, dSchema)
-	if len(alterEng) > 0 {
+		alter.addChange(changeTableCreate, table, "", fmtTableCreateSQL(sSchema))
+	} else if srcOptions, dstOptions := tableOptionsLine(sSchema), tableOptionsLine(dSchema); srcOptions != dstOptions {
+		// 比对引擎和字符集
+		alter.Type = alterTypeAlter
+		alter.addChange(changeTableOptions, table, dstOptions, srcOptions)
+	} else if sc.getSchemaDiff(alter); len(alter.Changes) > 0 {
 		alter.Type = alterTypeAlter
-		alter.SQL = append(alter.SQL, alterEng)
-		return alter
 	}
+	alter.SQL = alterTableSQL(table, alter.Changes, cfg.SingleSchemaChange)
+	return alter
+}
 
-	diffLines := sc.getSchemaDiff(alter)
-	if len(diffLines) == 0 {
-		return alter
-	}
-	alter.Type = alterTypeAlter
-	if cfg.SingleSchemaChange {
-		for _, line := range diffLines {
-			ns := fmt.Sprintf("ALTER TABLE `%s`\n%s;", table, line)
-			alter.SQL = append(alter.SQL, ns)
-		}
-	} else {
-		ns := fmt.Sprintf("ALTER TABLE `%s`\n%s;", table, strings.Join(diffLines, ",\n"))
-		alter.SQL = append(alter.SQL, ns)
+// drop 时备份的表名，不再当作多余的表处理
+var droppedTableReg = regexp.MustCompile(`_dropped_\d{8}(_\d+)?$`)
+
+// backupTableName drop 时备份的表名 表名_dropped_日期，同一天已有备份时加上序号 _2、_3
+func (sc *SchemaSync) backupTableName(table string) string {
+	backup := fmt.Sprintf("%s_dropped_%s", table, time.Now().Format("20060102"))
+	tables := sc.DestDb.GetTableNames()
+	name := backup
+	for i := 2; inStringSlice(name, tables); i++ {
+		name = fmt.Sprintf("%s_%d", backup, i)
 	}
+	return name
+}
 
-	return alter
+// setDropTableSQL 处理目标数据库多余的表，只有 drop 时才删除，
+// 默认拒绝删除有数据的表，配置 drop_table_backup 时重命名为备份表
+func (sc *SchemaSync) setDropTableSQL(sd *TableAlterData, cfg *Config) {
+	table := sd.Table
+	switch {
+	case !cfg.Drop:
+		sd.Comment = "源数据库不存在，使用 -drop 删除目标数据库多余的表"
+		sd.SQL = nil
+	case cfg.DropTableBackup:
+		backup := sc.backupTableName(table)
+		sd.Comment = "源数据库不存在，目标数据库多余的表重命名为 " + backup
+		sd.SQL = []string{fmt.Sprintf("RENAME TABLE `%s` TO `%s`;", table, backup)}
+	case !cfg.DropTableNonEmpty && !sc.DestDb.IsTableEmpty(table):
+		sd.Comment = "源数据库不存在，目标数据库多余的表有数据，拒绝删除（可配置 drop_table_non_empty 或 drop_table_backup）"
+		sd.SQL = nil
+	}
 }
 
-func (sc *SchemaSync) getSchemaDiff(alter *TableAlterData) []string {
+// getSchemaDiff 对比字段、索引和外键，变更项记录到 alter.Changes
+func (sc *SchemaSync) getSchemaDiff(alter *TableAlterData) {
 	sourceMyS := alter.SchemaDiff.Source
 	destMyS := alter.SchemaDiff.Dest
 	var beforeFieldName string
-	var alterLines []string
-	var fieldCount int = 0
+
+	// 重命名的字段，旧字段名 => 新字段名
+	renames := sc.getColumnRenames(alter)
+	renameFrom := make(map[string]string, len(renames))
+	for oldName, newName := range renames {
+		renameFrom[newName] = oldName
+	}
+
 	// 比对字段
 	for el := sourceMyS.Fields.Front(); el != nil; el = el.Next() {
-		var alterSQL string
-		if destDt, has := destMyS.Fields.Get(el.Key); has {
-			if el.Value != destDt {
-				alterSQL = fmt.Sprintf("CHANGE `%s` %s", el.Key, el.Value)
+		name, def := el.Key.(string), el.Value.(string)
+		if oldName, has := renameFrom[name]; has {
+			oldDt, _ := destMyS.Fields.Get(oldName)
+			alter.addChange(changeColumnRename, name, oldDt.(string), def).From = oldName
+		} else if destDt, has := destMyS.Fields.Get(name); has {
+			if def != destDt {
+				alter.addChange(changeColumnChange, name, destDt.(string), def)
 			}
-			beforeFieldName = el.Key.(string)
 		} else {
-			if len(beforeFieldName) == 0 {
-				if fieldCount == 0 {
-					alterSQL = "ADD " + el.Value.(string) + " FIRST"
-				} else {
-					alterSQL = "ADD " + el.Value.(string)
-				}
-			} else {
-				alterSQL = fmt.Sprintf("ADD %s AFTER `%s`", el.Value.(string), beforeFieldName)
+			ch := alter.addChange(changeColumnAdd, name, "", def)
+			ch.Position = "FIRST"
+			if len(beforeFieldName) > 0 {
+				ch.Position = fmt.Sprintf("AFTER `%s`", beforeFieldName)
 			}
-			beforeFieldName = el.Key.(string)
-		}
-
-		if len(alterSQL) != 0 {
-			alterLines = append(alterLines, alterSQL)
 		}
-		fieldCount++
+		beforeFieldName = name
 	}
 
 	// 源库已经删除的字段
 	if sc.Config.Drop {
-		for _, name := range destMyS.Fields.Keys() {
-			if _, has := sourceMyS.Fields.Get(name); !has {
-				alterSQL := fmt.Sprintf("drop `%s`", name)
-				alterLines = append(alterLines, alterSQL)
+		var prev string
+		for _, key := range destMyS.Fields.Keys() {
+			name := key.(string)
+			_, renamed := renames[name]
+			if _, has := sourceMyS.Fields.Get(name); !has && !renamed {
+				destDt, _ := destMyS.Fields.Get(name)
+				ch := alter.addChange(changeColumnDrop, name, destDt.(string), "")
+				ch.Position = "FIRST"
+				if len(prev) > 0 {
+					ch.Position = fmt.Sprintf("AFTER `%s`", prev)
+				}
 			}
+			prev = name
 		}
 	}
 
-	// 多余的字段暂不删除
-
 	// 比对索引
 	for indexName, idx := range sourceMyS.IndexAll {
 		dIdx, has := destMyS.IndexAll[indexName]
-		var alterSQLs []string
-		if has {
-			if idx.SQL != dIdx.SQL {
-				alterSQLs = append(alterSQLs, idx.alterAddSQL(true)...)
-			}
-		} else {
-			alterSQLs = append(alterSQLs, idx.alterAddSQL(false)...)
-		}
-		if len(alterSQLs) > 0 {
-			alterLines = append(alterLines, alterSQLs...)
+		if !has {
+			alter.addChange(changeIndexAdd, indexName, "", idx.SQL)
+		} else if idx.SQL != renameIndexColumns(dIdx.SQL, renames) {
+			alter.addChange(changeIndexReplace, indexName, dIdx.SQL, idx.SQL)
 		}
 	}
 
 	// drop index
 	if sc.Config.Drop {
 		for indexName, dIdx := range destMyS.IndexAll {
-			var dropSQL string
 			if _, has := sourceMyS.IndexAll[indexName]; !has {
-				dropSQL = dIdx.alterDropSQL()
-			}
-
-			if len(dropSQL) != 0 {
-				alterLines = append(alterLines, dropSQL)
+				alter.addChange(changeIndexDrop, indexName, dIdx.SQL, "")
 			}
 		}
 	}
@@ -208,187 +245,63 @@ func (sc *SchemaSync) getSchemaDiff(alter *TableAlterData) []string {
 	// 比对外键
 	for foreignName, idx := range sourceMyS.ForeignAll {
 		dIdx, has := destMyS.ForeignAll[foreignName]
-		var alterSQLs []string
-		if has {
-			if idx.SQL != dIdx.SQL {
-				alterSQLs = append(alterSQLs, idx.alterAddSQL(true)...)
-			}
-		} else {
-			alterSQLs = append(alterSQLs, idx.alterAddSQL(false)...)
-		}
-		if len(alterSQLs) > 0 {
-			alterLines = append(alterLines, alterSQLs...)
+		if !has {
+			alter.addChange(changeForeignAdd, foreignName, "", idx.SQL)
+		} else if idx.SQL != renameIndexColumns(dIdx.SQL, renames) {
+			alter.addChange(changeForeignReplace, foreignName, dIdx.SQL, idx.SQL)
 		}
 	}
 
 	// drop 外键
 	if sc.Config.Drop {
 		for foreignName, dIdx := range destMyS.ForeignAll {
-			var dropSQL string
 			if _, has := sourceMyS.ForeignAll[foreignName]; !has {
-				dropSQL = dIdx.alterDropSQL()
-			}
-			if len(dropSQL) != 0 {
-				alterLines = append(alterLines, dropSQL)
+				alter.addChange(changeForeignDrop, foreignName, dIdx.SQL, "")
 			}
 		}
 	}
-
-	return alterLines
 }
 
-// 返回格式：ALTER TABLE `auth_group_menu` ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
-func (sc *SchemaSync) getTableEngDiff(table string, srcSchemaRaw string, dstSchemaRaw string) string {
-	srcSchema := strings.Split(srcSchemaRaw, "\n")
-	dstSchema := strings.Split(dstSchemaRaw, "\n")
-	srcTableEng := srcSchema[len(srcSchema)-1]
-	dstTableEng := dstSchema[len(dstSchema)-1]
-
-	// 去除字符串中 AUTO_xx=xxx 部分
-	re := regexp.MustCompile(`AUTO_INCREMENT=\d+\s*`)
-	srcTableEng = re.ReplaceAllString(srcTableEng, "")
-	dstTableEng = re.ReplaceAllString(dstTableEng, "")
-
-	if srcTableEng != dstTableEng {
-		nameAlter := fmt.Sprintf("ALTER TABLE `%s`", table)
-		return strings.Replace(srcTableEng, ")", nameAlter, 1)
-	}
-	return ""
+var autoIncrOptionReg = regexp.MustCompile(`AUTO_INCREMENT=\d+\s*`)
+
+// tableOptionsLine 建表语句的最后一行（引擎、字符集等），已去除 AUTO_INCREMENT=xxx 部分
+func tableOptionsLine(schema string) string {
+	lines := strings.Split(schema, "\n")
+	return autoIncrOptionReg.ReplaceAllString(lines[len(lines)-1], "")
 }
 
-// SyncSQL4Dest sync schema change
+// SyncSQL4Dest 拆分 sql 脚本并在目标库逐条执行
 func (sc *SchemaSync) SyncSQL4Dest(sqlStr string) error {
-	sqlStr = strings.TrimSpace(sqlStr)
-	if len(sqlStr) == 0 {
-		return nil
-	}
-	t := newMyTimer()
-	sqls := strings.Split(string(sqlStr), ";\n")
-
-	tx, err := sc.DestDb.Db.Begin()
-	if err != nil {
-		log.Println("trans Begin err: ", err)
-		return err
+	if sc.DestDb.snapshot != nil {
+		return errSnapshotQuery
 	}
-
-	for _, sql := range sqls {
-		_, err = tx.Query(sql)
-		if err != nil {
-			log.Println("error query_one:[", sql, "]", err)
-			break
-		}
-	}
-	if err == nil {
-		err = tx.Commit()
-	} else {
-		_ = tx.Rollback()
+	sqls := splitSQLScript(sqlStr)
+	if len(sqls) == 0 {
+		return nil
 	}
-
-	t.stop()
+	err := executor.exec(sc.DestDb, sqls)
 	if err != nil {
 		log.Println("EXEC_SQL_FAILED:", err)
 	}
 	return err
 }
 
-// check data change
-func (sc *SchemaSync) CheckDiffData(cfg *Config) {
-	if len(cfg.TablesCompareData) == 0 {
-		fmt.Println("# Tables to CompareData is empty")
-		return
-	}
-
-	// sc := NewSchemaSync(cfg)
-	allTables := sc.SourceDb.GetTableNames()
-	dstTables := sc.DestDb.GetTableNames()
-
-	dataDiffTables := []string{}
-
-	for _, table := range allTables {
-		if !cfg.CheckMatchCompareDataTables(table) {
-			continue
-		}
-		// 目标库没有此表
-		if !inStringSlice(table, dstTables) {
-			dataDiffTables = append(dataDiffTables, table)
-			continue
-		}
-
-		// 查询第一个表
-		rows1, err := sc.SourceDb.Query(fmt.Sprintf("CHECKSUM TABLE `%s`", table))
-		if err != nil {
-			log.Fatal("failed to fetch line data: ", err)
-		}
-		defer rows1.Close()
-
-		rows2, err := sc.DestDb.Query(fmt.Sprintf("CHECKSUM TABLE `%s`", table))
-		if err != nil {
-			log.Fatal("failed to fetch line data: ", err)
-		}
-		defer rows2.Close()
-
-		// 比较两个表的数据
-		var tab1, tab2 string
-		var c1, c2 int64
-
-		rows1.Next()
-		rows2.Next()
-		rows1.Scan(&tab1, &c1)
-		rows2.Scan(&tab2, &c2)
-
-		if c1 != c2 {
-			dataDiffTables = append(dataDiffTables, table)
-			continue
-		}
-	}
-
-	if len(dataDiffTables) == 0 {
-		fmt.Println("# no data of tables in difference")
-	} else {
-		fmt.Println("# !!!!! data diff tables: ", strings.Join(dataDiffTables, ", "))
-	}
-}
-
-// check procedures
-func CheckAlterProcedure(cfg *Config) {
-	sc := NewSchemaSync(cfg)
-	allProcedures := sc.SourceDb.GetProcedureNames()
-
-	for _, proceName := range allProcedures {
-		srcProcedureStr := sc.SourceDb.GetProcedureSchema(proceName, true)
-		dstProcedureStr := sc.DestDb.GetProcedureSchema(proceName, false)
-
-		if srcProcedureStr != dstProcedureStr {
-			// 不支持直接执行语句 DELIMITER $$
-			sqlDrop := fmt.Sprintf("DROP PROCEDURE IF EXISTS `%s`", proceName)
-
-			// 输出
-			fmt.Printf("DELIMITER $$\n%s$$\n%s$$\nDELIMITER ;\n\n", sqlDrop, srcProcedureStr)
-
-			// 直接执行同步
-			if sc.Config.Sync {
-				if _, err := sc.DestDb.Db.Exec(sqlDrop); err != nil {
-					log.Fatalln("exec procedure failed", sqlDrop, err)
-				}
-				if _, err := sc.DestDb.Db.Exec(srcProcedureStr); err != nil {
-					log.Fatalln("exec procedure failed", srcProcedureStr, err)
-				}
-			}
-		}
-	}
-}
-
 // CheckSchemaDiff 执行最终的 diff
 func CheckSchemaDiff(cfg *Config) {
-	scs := newStatics(cfg)
+	sc := NewSchemaSync(cfg)
+	scs := newStatics(cfg, sc.DestDb.DbName)
+	staticsAll = append(staticsAll, scs)
 	defer func() {
 		scs.timer.stop()
+		writeHTMLResult(cfg)
 	}()
 
-	sc := NewSchemaSync(cfg)
 	newTables := sc.GetTableNames()
 	changedTables := make(map[string][]*TableAlterData)
+	var records []*tableRecord
+	recordOf := make(map[*TableAlterData]*tableRecord)
 
+	var sds []*TableAlterData
 	for _, table := range newTables {
 		if !cfg.CheckMatchTables(table) {
 			continue
@@ -399,24 +312,76 @@ func CheckSchemaDiff(cfg *Config) {
 		}
 
 		sd := sc.getAlterDataByTable(table, cfg)
+		if sd.Type == alterTypeDropTable && droppedTableReg.MatchString(sd.Table) {
+			continue
+		}
+		sds = append(sds, sd)
+	}
+	detectTableRenames(sds, cfg)
 
+	for _, sd := range sds {
 		if sd.Type == alterTypeNo {
 			continue
 		}
 
+		if sd.Type == alterTypeAlter && cfg.Online {
+			sc.setOnlineSQL(sd, cfg)
+		}
+
 		if sd.Type == alterTypeDropTable {
-			continue
+			sc.setDropTableSQL(sd, cfg)
+			if len(sd.SQL) == 0 {
+				output.printf("-- Table : %s\n-- %s\n\n", sd.Table, sd.Comment)
+				rec := newTableRecord(sd)
+				rec.Risk = classifyRisk(sd)
+				output.addTable(rec)
+				// 不删除的表也在 html 报告中列出
+				scs.newTableStatics(sd.Table, sd, 0).timer.stop()
+				continue
+			}
 		}
 
-		fmt.Println(sd)
-		fmt.Println("")
+		classifyRisk(sd)
+		checks := sc.preflight(sd, cfg)
+		risk := sd.risk()
+		blocked := cfg.riskBlocked(sd)
+
+		output.println(sd)
+		if sd.Type == alterTypeAlter && len(sd.Comment) > 0 {
+			output.printf("-- %s\n", sd.Comment)
+		}
+		if sd.Type == alterTypeCreate && len(sd.RenameFrom) > 0 {
+			output.printf("-- 可能由表 `%s` 重命名而来，使用 -drop 时将生成：RENAME TABLE `%s` TO `%s`;\n", sd.RenameFrom, sd.RenameFrom, sd.Table)
+		}
+		output.printf("%s", preflightComment(checks))
+		output.printf("%s", riskComment(sd, risk))
+		if len(blocked) > 0 {
+			output.printf("-- 风险超过 -max_risk=%s，-sync 时不执行，确认后使用 -allow %s 或 -allow %s.%s 允许\n",
+				cfg.MaxRisk, sd.Table, sd.Table, blocked[0].Name)
+		}
+		output.println("")
+		rec := newTableRecord(sd)
+		rec.Risk = risk
+		rec.Preflight = checks
+		records = append(records, rec)
+		recordOf[sd] = rec
+		if len(blocked) > 0 && cfg.Sync {
+			rec.Status = "blocked"
+			for index := range sd.SQL {
+				st := scs.newTableStatics(sd.Table, sd, index)
+				st.blocked = true
+				st.timer.stop()
+			}
+			log.Println("skip table", sd.Table, "risk", risk, "exceeds max_risk", cfg.MaxRisk)
+			continue
+		}
 		relationTables := sd.SchemaDiff.RelationTables()
 		// fmt.Println("relationTables:",table,relationTables)
 
 		// 将所有有外键关联的单独放
 		groupKey := "multi"
 		if len(relationTables) == 0 {
-			groupKey = "single_" + table
+			groupKey = "single_" + sd.Table
 		}
 		if _, has := changedTables[groupKey]; !has {
 			changedTables[groupKey] = make([]*TableAlterData, 0)
@@ -434,31 +399,60 @@ runSync:
 		if !strings.HasPrefix(typeName, canRunTypePref) {
 			continue
 		}
-		var sqls []string
 		var sts []*tableStatics
 		for _, sd := range sds {
+			rollback.addTableRollback(sd)
 			for index := range sd.SQL {
-				sql := strings.TrimRight(sd.SQL[index], ";")
-				sqls = append(sqls, sql)
-
 				st := scs.newTableStatics(sd.Table, sd, index)
 				sts = append(sts, st)
 			}
 		}
 
-		sql := strings.Join(sqls, ";\n") + ";"
 		var ret error
+		executed := sc.Config.Sync
 
 		if sc.Config.Sync {
-			ret = sc.SyncSQL4Dest(sql)
-			if ret == nil {
-				countSuccess++
-			} else {
+			trim := func(sqls []string) []string {
+				var trimmed []string
+				for _, sql := range sqls {
+					trimmed = append(trimmed, strings.TrimRight(sql, ";"))
+				}
+				return trimmed
+			}
+			for _, sd := range sds {
+				var err error
+				if sd.online == onlineShadow {
+					// 影子表复制的语句各自提交，出错时不再执行 RENAME，避免替换为缺少数据的新表
+					err = executor.execSequence(sc.DestDb, trim(sd.SQL), trim(shadowCleanupSQL(sd.Table)))
+				} else {
+					err = executor.exec(sc.DestDb, trim(sd.SQL))
+				}
+				if err != nil && ret == nil {
+					ret = err
+				}
+			}
+			status := stmtSuccess
+			switch {
+			case ret == errExecStopped:
+				status = stmtSkipped
+				executed = false
+			case ret != nil:
+				status = stmtFailed
 				countFailed++
+				drift.execErrors++
+			default:
+				countSuccess++
+			}
+			for _, sd := range sds {
+				recordOf[sd].Status = status
+				if ret != nil {
+					recordOf[sd].Error = ret.Error()
+				}
 			}
 		}
 		for _, st := range sts {
 			st.alterRet = ret
+			st.executed = executed
 			st.schemaAfter = sc.DestDb.GetTableSchema(st.table)
 			st.timer.stop()
 		}
@@ -470,38 +464,11 @@ runSync:
 		goto runSync
 	}
 
-	if sc.Config.Sync {
-		log.Println("execute_all_sql_done, success_total:", countSuccess, "failed_total:", countFailed)
+	for _, rec := range records {
+		output.addTable(rec)
 	}
-}
-
-// compare two sql result
-func CompareSqlResult(cfg *Config, sql string) {
-	sc := NewSchemaSync(cfg)
 
-	showResult := func(db *MyDb) {
-		dbx := sqlx.NewDb(db.Db, "mysql")
-		rows, err := dbx.Queryx(sql)
-		if err != nil {
-			log.Fatal("query error: ", err)
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var result = make(map[string]interface{}) // 使用map来存储列名和值对
-			err := rows.MapScan(result)
-			if err != nil {
-				log.Fatal("result scan failed: ", err)
-			}
-			for key, value := range result { // 遍历map的每个键值对
-				fmt.Printf("%s: %d\t", key, value) // 打印每个键值对，自定义格式化输出
-			}
-			fmt.Println("")
-		}
+	if sc.Config.Sync {
+		log.Println("execute_all_sql_done, success_total:", countSuccess, "failed_total:", countFailed)
 	}
-
-	fmt.Println("# sql result on sourceDSN")
-	showResult(sc.SourceDb)
-	fmt.Println("# sql result on destDSN")
-	showResult(sc.DestDb)
 }
@@ -5,7 +5,9 @@
 package internal
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -94,6 +96,50 @@ func TestSchemaSync_getAlterDataBySchema(t *testing.T) {
 			},
 			want: testLoadFile("testdata/result_4.sql"),
 		},
+		{
+			name: "user 5-6 rename",
+			args: args{
+				table:   "user",
+				sSchema: testLoadFile("testdata/user_5.sql"),
+				dSchema: testLoadFile("testdata/user_6.sql"),
+				cfg:     &Config{},
+			},
+			sc: &SchemaSync{
+				Config: &Config{Drop: true},
+			},
+			want: testLoadFile("testdata/result_5.sql"),
+		},
+		{
+			name: "user 5-6 rename with config",
+			args: args{
+				table:   "user",
+				sSchema: testLoadFile("testdata/user_5.sql"),
+				dSchema: testLoadFile("testdata/user_6.sql"),
+				cfg:     &Config{},
+			},
+			sc: &SchemaSync{
+				Config: &Config{
+					Drop: true,
+					Renames: map[string]map[string]string{
+						"user": {"password": "passwd"},
+					},
+				},
+			},
+			want: testLoadFile("testdata/result_6.sql"),
+		},
+		{
+			name: "user 1 table options",
+			args: args{
+				table:   "user",
+				sSchema: strings.Replace(testLoadFile("testdata/user_1.sql"), "CHARSET=utf8mb3", "CHARSET=utf8mb4", 1),
+				dSchema: testLoadFile("testdata/user_1.sql"),
+				cfg:     &Config{},
+			},
+			sc: &SchemaSync{
+				Config: &Config{},
+			},
+			want: testLoadFile("testdata/result_7.sql"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -103,3 +149,83 @@ func TestSchemaSync_getAlterDataBySchema(t *testing.T) {
 		})
 	}
 }
+
+func TestSchemaSync_getAlterDataBySchema_changes(t *testing.T) {
+	sc := &SchemaSync{
+		Config: &Config{Drop: true},
+	}
+	got := sc.getAlterDataBySchema("user", testLoadFile("testdata/user_1.sql"), testLoadFile("testdata/user_2.sql"), &Config{})
+	require.Equal(t, []*schemaChange{
+		{
+			Kind:   changeColumnChange,
+			Name:   "id",
+			Before: "`id` int(10) unsigned NOT NULL AUTO_INCREMENT",
+			After:  "`id` bigint unsigned NOT NULL AUTO_INCREMENT",
+		},
+		{
+			Kind:   changeColumnChange,
+			Name:   "email",
+			Before: "`email` varchar(100) NOT NULL DEFAULT ''",
+			After:  "`email` varchar(1000) NOT NULL DEFAULT ''",
+		},
+		{Kind: changeColumnDrop, Name: "register_time", Before: "`register_time` timestamp NOT NULL", Position: "AFTER `email`"},
+		{Kind: changeColumnDrop, Name: "password", Before: "`password` varchar(255) NOT NULL DEFAULT ''", Position: "AFTER `register_time`"},
+		{Kind: changeColumnDrop, Name: "status", Before: "`status` int(10) unsigned NOT NULL DEFAULT 1", Position: "AFTER `password`"},
+	}, got.Changes)
+}
+
+func TestSchemaSync_setDropTableSQL(t *testing.T) {
+	// 快照中没有数据，按有数据的表处理
+	sc := &SchemaSync{DestDb: &MyDb{snapshot: &snapshotSchema{}}}
+	schema := testLoadFile("testdata/user_1.sql")
+	backup := "user_dropped_" + time.Now().Format("20060102")
+
+	tests := []struct {
+		name    string
+		cfg     *Config
+		sql     []string
+		comment string
+	}{
+		{
+			name:    "no drop",
+			cfg:     &Config{},
+			comment: "源数据库不存在，使用 -drop 删除目标数据库多余的表",
+		},
+		{
+			name:    "backup",
+			cfg:     &Config{Drop: true, DropTableBackup: true},
+			sql:     []string{"RENAME TABLE `user` TO `" + backup + "`;"},
+			comment: "源数据库不存在，目标数据库多余的表重命名为 " + backup,
+		},
+		{
+			name:    "non empty",
+			cfg:     &Config{Drop: true},
+			comment: "源数据库不存在，目标数据库多余的表有数据，拒绝删除（可配置 drop_table_non_empty 或 drop_table_backup）",
+		},
+		{
+			name:    "drop non empty",
+			cfg:     &Config{Drop: true, DropTableNonEmpty: true},
+			sql:     []string{"drop table `user`;"},
+			comment: "源数据库不存在，删除目标数据库多余的表",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sd := sc.getAlterDataBySchema("user", "", schema, tt.cfg)
+			sc.setDropTableSQL(sd, tt.cfg)
+			require.Equal(t, tt.sql, sd.SQL)
+			require.Equal(t, tt.comment, sd.Comment)
+			if len(sd.SQL) == 0 {
+				// 不删除的表在 json 结果中仍按删除表的风险输出
+				require.Equal(t, riskDestructive, classifyRisk(sd))
+			}
+		})
+	}
+	require.True(t, droppedTableReg.MatchString(backup))
+
+	// 同一天再次备份时加上序号
+	sc.DestDb = &MyDb{snapshot: &snapshotSchema{Tables: map[string]string{backup: schema, backup + "_2": schema}}}
+	require.Equal(t, backup+"_3", sc.backupTableName("user"))
+	require.True(t, droppedTableReg.MatchString(backup+"_3"))
+	require.False(t, droppedTableReg.MatchString("user_dropped"))
+}
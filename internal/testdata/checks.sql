@@ -0,0 +1,11 @@
+-- 发版后的校验查询
+-- name: club_count
+select count(1) as cc from club;
+
+-- name: club_gold
+-- keys: club_id, day
+select club_id, day, sum(gold) as gold
+from club_log
+-- 只看最近7天
+where day > curdate() - interval 7 day
+group by club_id, day;
@@ -1,8 +1,4 @@
 -- Table : user
--- Type : alter
--- RelationTables :
--- Comment :
--- SQL :
 ALTER TABLE `user`
 ADD `register_time` timestamp NOT NULL AFTER `email`;
 ALTER TABLE `user`
@@ -1,5 +1 @@
--- Table : user
--- Type : not_change
--- RelationTables :
--- Comment :
--- SQL :
\ No newline at end of file
+-- Table : user
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- Table : user
+ALTER TABLE `user`
+CHANGE `email` `mail` varchar(1000) NOT NULL DEFAULT '',
+ADD `passwd` varchar(255) NOT NULL DEFAULT '' AFTER `register_time`,
+drop `password`;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- Table : user
+ALTER TABLE `user`
+CHANGE `email` `mail` varchar(1000) NOT NULL DEFAULT '',
+CHANGE `password` `passwd` varchar(255) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Table : user
+ALTER TABLE `user` ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
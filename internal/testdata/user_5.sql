@@ -0,0 +1,9 @@
+CREATE TABLE `user` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `mail` varchar(1000) NOT NULL DEFAULT '',
+    `register_time` timestamp NOT NULL,
+    `passwd` varchar(255) NOT NULL DEFAULT '',
+    `status` tinyint unsigned NOT NULL DEFAULT '0',
+    PRIMARY KEY (`id`),
+    KEY `idx_email` (`mail`)
+) ENGINE=InnoDB AUTO_INCREMENT=3 DEFAULT CHARSET=utf8mb3
\ No newline at end of file
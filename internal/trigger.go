@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// triggerSet 一个库中按表过滤后的触发器
+type triggerSet struct {
+	names []string
+	// tables 触发器名 => 所属的表
+	tables map[string]string
+	// schemas 触发器名 => 去除 DEFINER 后的定义
+	schemas map[string]string
+}
+
+// loadTriggers 读取库中按 tables、tables_ignore 过滤后的触发器，源库中对其他数据库的引用替换为目标库名
+func (sc *SchemaSync) loadTriggers(db *MyDb, cfg *Config) *triggerSet {
+	ts := &triggerSet{tables: make(map[string]string), schemas: make(map[string]string)}
+	names, tables := db.GetTriggerNames()
+	for _, name := range names {
+		if !cfg.CheckMatchTables(tables[name]) || cfg.CheckMatchIgnoreTables(tables[name]) {
+			continue
+		}
+		schema := db.GetTriggerSchema(name)
+		if db == sc.SourceDb {
+			schema = sc.rewriteSchemaRefs(schema)
+		}
+		ts.names = append(ts.names, name)
+		ts.tables[name] = tables[name]
+		ts.schemas[name] = stripDefiner(schema)
+	}
+	return ts
+}
+
+// diffTriggers 源库新增或变化的触发器先删除再创建，目标库多余的触发器在 drop 时删除
+func diffTriggers(src *triggerSet, dst *triggerSet, drop bool) []*routineRecord {
+	var changes []*routineRecord
+	for _, name := range src.names {
+		srcSchema, dstSchema := src.schemas[name], dst.schemas[name]
+		if srcSchema == dstSchema {
+			continue
+		}
+		rec := &routineRecord{
+			Name: name,
+			SQL:  []string{fmt.Sprintf("DROP TRIGGER IF EXISTS `%s`", name), srcSchema},
+			undo: []string{fmt.Sprintf("DROP TRIGGER IF EXISTS `%s`", name)},
+		}
+		if len(dstSchema) > 0 {
+			rec.Comment = "先删除再创建，创建失败时目标库将没有该触发器，可使用 -rollback 生成的语句恢复原定义"
+			rec.undo = append(rec.undo, dstSchema)
+		}
+		changes = append(changes, rec)
+	}
+
+	if drop {
+		for _, name := range dst.names {
+			if _, has := src.schemas[name]; has {
+				continue
+			}
+			changes = append(changes, &routineRecord{
+				Name: name,
+				SQL:  []string{fmt.Sprintf("DROP TRIGGER IF EXISTS `%s`", name)},
+				undo: []string{dst.schemas[name]},
+			})
+		}
+	}
+	return changes
+}
+
+// printTrigger 预览中输出触发器的变更，触发器体中可能包含分号，与存储过程一样使用 DELIMITER 输出
+func (o *resultOutput) printTrigger(rec *routineRecord) {
+	if len(rec.Comment) > 0 {
+		o.printf("-- Trigger `%s` : %s\n", rec.Name, rec.Comment)
+	}
+	o.printf("DELIMITER $$\n%s$$\nDELIMITER ;\n\n", strings.Join(rec.SQL, "$$\n"))
+}
+
+// CheckAlterTrigger 对比触发器，按触发器所属的表进行 tables、tables_ignore 过滤
+func CheckAlterTrigger(cfg *Config) {
+	sc := NewSchemaSync(cfg)
+	src, dst := sc.loadTriggers(sc.SourceDb, cfg), sc.loadTriggers(sc.DestDb, cfg)
+	changes := diffTriggers(src, dst, cfg.Drop)
+	for _, rec := range changes {
+		if _, has := src.schemas[rec.Name]; !has {
+			// 目标库多余的触发器，可以用触发器名或所属的表名 -allow
+			cfg.blockDrop(rec, rec.Name, dst.tables[rec.Name])
+		}
+	}
+
+	for _, rec := range changes {
+		output.printTrigger(rec)
+		output.addTrigger(rec)
+		rollback.addObjectRollback("Trigger", rec, true)
+
+		if !sc.Config.Sync || rec.Blocked {
+			continue
+		}
+		if err := executor.exec(sc.DestDb, rec.SQL); err != nil && err != errExecStopped {
+			log.Println("exec trigger failed", rec.Name, err)
+			if len(rec.undo) > 1 {
+				log.Println("trigger", rec.Name, "may have been dropped, original definition:\n"+rec.undo[1])
+			}
+			drift.execErrors++
+		}
+	}
+}
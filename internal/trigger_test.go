@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_diffTriggers(t *testing.T) {
+	src := &triggerSet{
+		names:   []string{"trg_a", "trg_b", "trg_c"},
+		tables:  map[string]string{"trg_a": "user", "trg_b": "user", "trg_c": "order"},
+		schemas: map[string]string{"trg_a": "CREATE TRIGGER `trg_a` v1", "trg_b": "CREATE TRIGGER `trg_b` v2", "trg_c": "CREATE TRIGGER `trg_c` v1"},
+	}
+	dst := &triggerSet{
+		names:   []string{"trg_a", "trg_b", "trg_d"},
+		tables:  map[string]string{"trg_a": "user", "trg_b": "user", "trg_d": "order"},
+		schemas: map[string]string{"trg_a": "CREATE TRIGGER `trg_a` v1", "trg_b": "CREATE TRIGGER `trg_b` v1", "trg_d": "CREATE TRIGGER `trg_d` v1"},
+	}
+
+	tests := []struct {
+		drop  bool
+		names []string
+	}{
+		{false, []string{"trg_b", "trg_c"}},
+		{true, []string{"trg_b", "trg_c", "trg_d"}},
+	}
+	for _, tt := range tests {
+		changes := diffTriggers(src, dst, tt.drop)
+		var names []string
+		for _, rec := range changes {
+			names = append(names, rec.Name)
+		}
+		require.Equal(t, tt.names, names)
+	}
+
+	changes := diffTriggers(src, dst, true)
+	// 修改的触发器先删除再创建，回滚时恢复原定义
+	require.Equal(t, []string{"DROP TRIGGER IF EXISTS `trg_b`", "CREATE TRIGGER `trg_b` v2"}, changes[0].SQL)
+	require.Equal(t, []string{"DROP TRIGGER IF EXISTS `trg_b`", "CREATE TRIGGER `trg_b` v1"}, changes[0].undo)
+	require.NotEmpty(t, changes[0].Comment)
+	// 新增的触发器
+	require.Equal(t, []string{"DROP TRIGGER IF EXISTS `trg_c`"}, changes[1].undo)
+	require.Empty(t, changes[1].Comment)
+	// 目标库多余的触发器
+	require.Equal(t, []string{"DROP TRIGGER IF EXISTS `trg_d`"}, changes[2].SQL)
+	require.Equal(t, []string{"CREATE TRIGGER `trg_d` v1"}, changes[2].undo)
+}
@@ -65,7 +65,7 @@ func decodePass(pass string) string {
 	// 使用url.QueryUnescape解码
 	decodes, err := url.QueryUnescape(pass)
 	if err != nil {
-		log.Fatalf("URL解码错误:", err)
+		log.Fatalln("URL解码错误:", err)
 	}
 	return string(decodes)
 }
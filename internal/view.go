@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// 视图定义中可忽略的子句
+const (
+	viewClauseDefiner     = "definer"
+	viewClauseAlgorithm   = "algorithm"
+	viewClauseSQLSecurity = "sql_security"
+)
+
+var viewClauseRegs = map[string]*regexp.Regexp{
+	viewClauseDefiner:     regexp.MustCompile("DEFINER=`[^`]*`@`[^`]*` "),
+	viewClauseAlgorithm:   regexp.MustCompile(`ALGORITHM=[A-Z]+ `),
+	viewClauseSQLSecurity: regexp.MustCompile(`SQL SECURITY (DEFINER|INVOKER) `),
+}
+
+// viewIgnoreClauses 对比视图时需要去除的子句
+func (cfg *Config) viewIgnoreClauses() []string {
+	if cfg.ViewIgnoreClauses == nil {
+		return []string{viewClauseDefiner, viewClauseAlgorithm, viewClauseSQLSecurity}
+	}
+	return cfg.ViewIgnoreClauses
+}
+
+// normalizeViewSchema 去除视图定义中不需要对比的子句
+// CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v_user` AS select ...
+func normalizeViewSchema(schema string, cfg *Config) string {
+	for _, clause := range cfg.viewIgnoreClauses() {
+		reg, has := viewClauseRegs[strings.ToLower(strings.TrimSpace(clause))]
+		if !has {
+			log.Fatalln("unsupported view_ignore_clauses:", clause)
+		}
+		schema = reg.ReplaceAllString(schema, "")
+	}
+	return strings.TrimSpace(schema)
+}
+
+// viewCreateSQL 将视图原始定义转换为 CREATE OR REPLACE VIEW 语句，只去除 DEFINER，保留 ALGORITHM 和 SQL SECURITY
+func viewCreateSQL(schema string) string {
+	schema = viewClauseRegs[viewClauseDefiner].ReplaceAllString(strings.TrimSpace(schema), "")
+	return strings.Replace(schema, "CREATE ", "CREATE OR REPLACE ", 1)
+}
+
+// sortViewsByDependency 按依赖关系排序，被引用的视图排在前面
+func sortViewsByDependency(names []string, schemas map[string]string) []string {
+	var sorted []string
+	visited := make(map[string]int)
+	var visit func(name string)
+	visit = func(name string) {
+		// 1:访问中，2:已完成，出现循环引用时不再递归
+		if visited[name] > 0 {
+			return
+		}
+		visited[name] = 1
+		for _, dep := range names {
+			if dep != name && strings.Contains(schemas[name], "`"+dep+"`") {
+				visit(dep)
+			}
+		}
+		visited[name] = 2
+		sorted = append(sorted, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return sorted
+}
+
+// printView 预览中输出视图的变更
+func (o *resultOutput) printView(rec *routineRecord) {
+	if rec.Blocked {
+		o.printf("-- View : %s : %s\n", rec.Name, rec.Comment)
+	}
+	o.printf("-- View : %s\n%s;\n\n", rec.Name, strings.Join(rec.SQL, ";\n"))
+}
+
+// CheckAlterView 对比视图，源库新增或变化的视图重建，目标库多余的视图在 drop 时删除
+func CheckAlterView(cfg *Config) {
+	sc := NewSchemaSync(cfg)
+
+	matchView := func(name string) bool {
+		return cfg.CheckMatchTables(name) && !cfg.CheckMatchIgnoreTables(name)
+	}
+
+	var srcViews []string
+	// srcRaws 源库视图的原始定义，用于生成语句；srcSchemas 去除忽略的子句后用于对比
+	srcRaws := make(map[string]string)
+	srcSchemas := make(map[string]string)
+	for _, name := range sc.SourceDb.GetViewNames() {
+		if !matchView(name) {
+			continue
+		}
+		srcViews = append(srcViews, name)
+		srcRaws[name] = sc.rewriteSchemaRefs(sc.SourceDb.GetViewSchema(name))
+		srcSchemas[name] = normalizeViewSchema(srcRaws[name], cfg)
+	}
+	dstViews := sc.DestDb.GetViewNames()
+
+	var changes []*routineRecord
+	for _, name := range sortViewsByDependency(srcViews, srcSchemas) {
+		var dstRaw, dstSchema string
+		if inStringSlice(name, dstViews) {
+			dstRaw = sc.DestDb.GetViewSchema(name)
+			dstSchema = normalizeViewSchema(dstRaw, cfg)
+		}
+		if srcSchemas[name] == dstSchema {
+			continue
+		}
+		// 回滚时使用目标库的原始定义，保留 ALGORITHM 和 SQL SECURITY
+		undo := []string{fmt.Sprintf("DROP VIEW IF EXISTS `%s`", name)}
+		if len(dstRaw) > 0 {
+			undo = []string{viewCreateSQL(dstRaw)}
+		}
+		changes = append(changes, &routineRecord{
+			Name: name,
+			SQL:  []string{viewCreateSQL(srcRaws[name])},
+			undo: undo,
+		})
+	}
+
+	if cfg.Drop {
+		for _, name := range dstViews {
+			if !matchView(name) || inStringSlice(name, srcViews) {
+				continue
+			}
+			rec := &routineRecord{
+				Name: name,
+				SQL:  []string{fmt.Sprintf("DROP VIEW IF EXISTS `%s`", name)},
+				undo: []string{viewCreateSQL(sc.DestDb.GetViewSchema(name))},
+			}
+			cfg.blockDrop(rec, name)
+			changes = append(changes, rec)
+		}
+	}
+
+	for _, rec := range changes {
+		output.printView(rec)
+		output.addView(rec)
+		rollback.addObjectRollback("View", rec, false)
+
+		if !sc.Config.Sync || rec.Blocked {
+			continue
+		}
+		if err := executor.exec(sc.DestDb, rec.SQL); err != nil && err != errExecStopped {
+			log.Println("exec view failed", rec.Name, err)
+			drift.execErrors++
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_normalizeViewSchema(t *testing.T) {
+	schema := "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v_user` AS select `u`.`id` AS `id` from `user` `u`"
+
+	got := normalizeViewSchema(schema, &Config{})
+	require.Equal(t, "CREATE VIEW `v_user` AS select `u`.`id` AS `id` from `user` `u`", got)
+	require.Equal(t, "CREATE OR REPLACE VIEW `v_user` AS select `u`.`id` AS `id` from `user` `u`", viewCreateSQL(got))
+	// 生成语句时只去除 DEFINER，保留 SQL SECURITY 等
+	require.Equal(t, "CREATE OR REPLACE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v_user` AS select `u`.`id` AS `id` from `user` `u`", viewCreateSQL(schema))
+	require.Equal(t, "CREATE OR REPLACE SQL SECURITY INVOKER VIEW `v` AS select 1 AS `1`",
+		viewCreateSQL("CREATE DEFINER=`root`@`%` SQL SECURITY INVOKER VIEW `v` AS select 1 AS `1`"))
+
+	got = normalizeViewSchema(schema, &Config{ViewIgnoreClauses: []string{"definer"}})
+	require.Equal(t, "CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v_user` AS select `u`.`id` AS `id` from `user` `u`", got)
+}
+
+func Test_sortViewsByDependency(t *testing.T) {
+	schemas := map[string]string{
+		"v_a": "CREATE VIEW `v_a` AS select `v_b`.`id` AS `id` from `v_b`",
+		"v_b": "CREATE VIEW `v_b` AS select `v_c`.`id` AS `id` from `v_c`",
+		"v_c": "CREATE VIEW `v_c` AS select `user`.`id` AS `id` from `user`",
+		"v_d": "CREATE VIEW `v_d` AS select `user`.`id` AS `id` from `user`",
+	}
+	got := sortViewsByDependency([]string{"v_a", "v_d", "v_b", "v_c"}, schemas)
+	require.Equal(t, []string{"v_c", "v_b", "v_a", "v_d"}, got)
+}
@@ -7,15 +7,29 @@ import (
 	"mysql-sync/internal"
 	"os"
 	"runtime"
+	"strings"
 )
 
 var configPath = flag.String("conf", "./conf.json", "json config file path")
 var sync = flag.Bool("sync", false, "sync schema changes to dest's db\non default, only show difference")
+var syncData = flag.Bool("sync_data", false, "generate insert,update,delete sql to make data of tables_compare_data same as source\nuse with -sync to execute, with -drop to delete rows only on dest")
 var drop = flag.Bool("drop", false, "drop fields,index,foreign key only on dest's table")
 var singleSchemaChange = flag.Bool("single_schema_change", false, "single schema changes ddl command a single schema change")
+var check = flag.Bool("check", false, "exit with a non-zero code when differences are found:\n10 schema drift, 11 data drift, 12 procedure drift, 13 execution error")
+var format = flag.String("format", "text", "output format of differences: text, json, ndjson")
 
 var sql2compare = flag.String("sql_check", "", "sql to compare result on both dsn")
+var sqlCheckKey = flag.String("sql_check_key", "", "comma separated key columns of sql_check result to match rows\non default, match by full row")
+var sqlCheckFile = flag.String("sql_check_file", "", "file of named sql to compare result on both dsn\n.yaml/.yml: list of {name, sql, keys}, others: sql with `-- name:` and `-- keys:` comments")
 var sqlFile = flag.String("sql_file", "", "sql file path")
+var onError = flag.String("on_error", "stop", "when a statement fails on dest: stop, continue")
+var online = flag.Bool("online", false, "alter large tables online: ALGORITHM=INPLACE/INSTANT, LOCK=NONE or shadow table copy")
+var preflight = flag.Bool("preflight", false, "count rows on dest that would be truncated or break new unique keys before altering\nfull table scans, limited by preflight_timeout in config")
+var journal = flag.String("journal", "", "journal file to record every statement executed on dest")
+var resume = flag.Bool("resume", false, "skip statements already applied according to -journal")
+var maxRisk = flag.String("max_risk", "", "highest risk of table changes to apply with -sync: safe, rebuild, lossy, destructive\non default, no limit")
+var allow = flag.String("allow", "", "comma separated tables or table.column/index allowed to exceed -max_risk, * is supported")
+var dumpSchema = flag.String("dump_schema", "", "save schemas of source to a snapshot file, use it as source or dest with file://path")
 
 func init() {
 	log.SetFlags(log.Lshortfile | log.Ldate)
@@ -30,11 +44,31 @@ func init() {
 
 var cfg *internal.Config
 
+// splitFlag 拆分逗号分隔的参数，去除空白和空项
+func splitFlag(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // 对比两个dsn下的数据库
 func compareDSN() {
 	cfg.Sync = *sync
 	cfg.Drop = *drop
+	cfg.SyncData = *syncData
 	cfg.SingleSchemaChange = *singleSchemaChange
+	cfg.Format = *format
+	cfg.OnError = *onError
+	cfg.Journal = *journal
+	cfg.Resume = *resume
+	cfg.Online = *online
+	cfg.Preflight = *preflight
+	cfg.MaxRisk = *maxRisk
+	cfg.Allow = splitFlag(*allow)
 	cfg.Check()
 
 	syncInstance := internal.NewSchemaSync(cfg)
@@ -42,8 +76,17 @@ func compareDSN() {
 	for _, _dbname := range cfg.Schemas {
 		syncInstance.UseDb(_dbname)
 		syncInstance.CheckDiffData(cfg)
-		internal.CheckAlterProcedure(cfg)
+		internal.CheckAlterRoutine(cfg)
 		internal.CheckSchemaDiff(cfg)
+		internal.CheckAlterTrigger(cfg)
+		internal.CheckAlterView(cfg)
+		syncInstance.SyncDiffData(cfg)
+	}
+	internal.ExecReport()
+	internal.FlushOutput()
+	internal.WriteRollback()
+	if *check {
+		os.Exit(internal.CheckResult())
 	}
 }
 
@@ -54,9 +97,14 @@ func importSQL(file string) {
 		log.Fatal("read sql file failed: ", err)
 	}
 	sqlStr := string(sqls)
+	cfg.OnError = *onError
+	cfg.Journal = *journal
+	cfg.Resume = *resume
+	cfg.Check()
 
 	sc := internal.NewSchemaSync(cfg)
 	err = sc.SyncSQL4Dest(sqlStr)
+	internal.ExecReport()
 	if err != nil {
 		log.Fatalf("execute failed, error: %v\n", err)
 	}
@@ -68,7 +116,9 @@ func compareSQL() {
 	if len(*sql2compare) <= 0 {
 		log.Fatalln("param `sql_check` is necessary")
 	}
-	internal.CompareSqlResult(cfg, *sql2compare)
+	if !internal.CompareSqlResult(cfg, *sql2compare, splitFlag(*sqlCheckKey)) {
+		os.Exit(internal.ExitDataDrift)
+	}
 }
 
 func main() {
@@ -84,7 +134,15 @@ func main() {
 		}
 	})()
 
-	if len(*sql2compare) > 0 {
+	if len(*dumpSchema) > 0 {
+		// 保存源库的结构快照
+		internal.DumpSchema(cfg, *dumpSchema)
+	} else if len(*sqlCheckFile) > 0 {
+		// 批量对比sql的执行结果
+		if !internal.CompareSqlFile(cfg, *sqlCheckFile) {
+			os.Exit(internal.ExitDataDrift)
+		}
+	} else if len(*sql2compare) > 0 {
 		// 对比sql的执行结果
 		compareSQL()
 	} else if len(*sqlFile) > 0 {